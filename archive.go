@@ -0,0 +1,186 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveSuffixes are the file name endings of the archives md can browse.
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// isArchive reports whether path names an archive md can browse, by its
+// extension.
+func isArchive(path string) bool {
+	name := strings.ToLower(path)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchiveFile reports whether path is a regular file that md can browse as
+// an archive. Directories are never archives, whatever their name.
+func isArchiveFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && isArchive(path)
+}
+
+// treeFS reads the files of a tree by their paths. The zero value reads
+// from the OS; the files of an archive appear below the archive's own path,
+// as if it were a directory.
+type treeFS struct {
+	archive string // Absolute path of the archive, or "" for the OS
+	files   fs.FS  // Contents of the archive
+}
+
+// openArchive reads the contents of a zip or tar archive into memory.
+func openArchive(archivePath string) (treeFS, error) {
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return treeFS{}, err
+	}
+
+	var files fs.FS
+	name := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		files, err = zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(name, ".tar"):
+		files, err = readTar(bytes.NewReader(data))
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			files, err = readTar(gz)
+		}
+	default:
+		err = errors.New("unsupported archive type")
+	}
+	if err != nil {
+		return treeFS{}, fmt.Errorf("%s: %w", archivePath, err)
+	}
+
+	return treeFS{archive: absPath(archivePath), files: files}, nil
+}
+
+// readTar reads the regular files of a tar archive. They are copied into an
+// uncompressed zip in memory, so that both kinds of archive are read the same
+// way: the directories between files are implied and links are left out.
+func readTar(r io.Reader) (fs.FS, error) {
+	var buf bytes.Buffer
+	files := zip.NewWriter(&buf)
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if header.Typeflag != tar.TypeReg || !fs.ValidPath(name) || name == "." {
+			continue
+		}
+		w, err := files.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: header.ModTime})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, archive); err != nil {
+			return nil, err
+		}
+	}
+	if err := files.Close(); err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// name returns the name of path within the archive.
+func (t treeFS) name(op, filePath string) (string, error) {
+	rel, err := filepath.Rel(t.archive, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: op, Path: filePath, Err: fs.ErrNotExist}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (t treeFS) ReadFile(filePath string) ([]byte, error) {
+	if t.archive == "" {
+		return os.ReadFile(filePath)
+	}
+	name, err := t.name("open", filePath)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(t.files, name)
+}
+
+func (t treeFS) Stat(filePath string) (fs.FileInfo, error) {
+	if t.archive == "" {
+		return os.Stat(filePath)
+	}
+	name, err := t.name("stat", filePath)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(t.files, name)
+}
+
+func (t treeFS) ReadDir(filePath string) ([]fs.DirEntry, error) {
+	if t.archive == "" {
+		return os.ReadDir(filePath)
+	}
+	name, err := t.name("open", filePath)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(t.files, name)
+}
+
+// sub returns the file system of the directory at path.
+func (t treeFS) sub(dir string) (fs.FS, error) {
+	if t.archive == "" {
+		return os.DirFS(dir), nil
+	}
+	name, err := t.name("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Sub(t.files, name)
+}
+
+// entryFilter returns the filter for entries under root. Ignore files on
+// disk don't apply to an archive's contents.
+func (t treeFS) entryFilter(root string, opts FinderOptions) *entryFilter {
+	if t.archive == "" {
+		return newEntryFilter(root, opts)
+	}
+	return &entryFilter{
+		includes: newPatternSet(root, opts.Include),
+		excludes: newPatternSet(root, opts.Exclude),
+		hidden:   opts.IncludeHidden,
+	}
+}
+
+// findMarkdownFiles builds the tree of markdown files under root down to
+// maxDepth, like FindMarkdownFilesWithOptions.
+func (t treeFS) findMarkdownFiles(root string, opts FinderOptions, maxDepth int) (*FileNode, error) {
+	files, err := t.sub(root)
+	if err != nil {
+		return nil, err
+	}
+	return walkMarkdownFiles(files, root, t.entryFilter(root, opts), maxDepth)
+}
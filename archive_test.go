@@ -0,0 +1,188 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var archiveFixture = map[string]string{
+	"README.md":          "# Bundle\n\nSee the [guide](docs/guide.md).\n",
+	"docs/guide.md":      "# Guide\n\nInstall it.\n",
+	"docs/api/ref.md":    "# Reference\n",
+	"docs/logo.png":      "",
+	".hidden/secret.md":  "# Secret\n",
+	"other/notes.txt":    "not markdown",
+	"other/deep/more.md": "# More\n",
+}
+
+func writeZipFixture(t *testing.T, path string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for name, content := range archiveFixture {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func writeTarGzFixture(t *testing.T, path string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+	for name, content := range archiveFixture {
+		header := &tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		archive.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"docs-bundle.zip":     true,
+		"release.tar.gz":      true,
+		"release.TGZ":         true,
+		"plain.tar":           true,
+		"README.md":           false,
+		"notes.zip.md":        false,
+		"dir/archive.tar.bz2": false,
+	} {
+		if isArchive(path) != expected {
+			t.Errorf("isArchive(%q): expected %t", path, expected)
+		}
+	}
+}
+
+func TestOpenArchive(t *testing.T) {
+	tempDir := t.TempDir()
+
+	for _, name := range []string{"bundle.zip", "release.tar.gz"} {
+		path := filepath.Join(tempDir, name)
+		if strings.HasSuffix(name, ".zip") {
+			writeZipFixture(t, path)
+		} else {
+			writeTarGzFixture(t, path)
+		}
+
+		fsys, err := openArchive(path)
+		if err != nil {
+			t.Fatalf("openArchive(%s) failed: %v", name, err)
+		}
+
+		tree, err := fsys.findMarkdownFiles(path, FinderOptions{}, -1)
+		if err != nil {
+			t.Fatalf("findMarkdownFiles(%s) failed: %v", name, err)
+		}
+		lines := FlattenTree(PruneEmptyDirs(tree), "", false)
+		expected := []string{"docs/", "api/", "ref.md", "guide.md", "other/", "deep/", "more.md", "README.md"}
+		if len(lines) != len(expected) {
+			t.Fatalf("%s: expected %d lines, got %v", name, len(expected), lines)
+		}
+		for i, suffix := range expected {
+			if !strings.HasSuffix(lines[i], suffix) {
+				t.Errorf("%s line %d: expected %s, got %q", name, i, suffix, lines[i])
+			}
+		}
+
+		// Files are read from inside the archive by their paths below it
+		content, err := fsys.ReadFile(filepath.Join(path, "docs", "guide.md"))
+		if err != nil || string(content) != archiveFixture["docs/guide.md"] {
+			t.Errorf("%s: expected the guide's content, got %q (%v)", name, content, err)
+		}
+		if _, err := fsys.ReadFile(filepath.Join(tempDir, "outside.md")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected paths outside the archive not to exist, got %v", name, err)
+		}
+	}
+
+	if _, err := openArchive(filepath.Join(tempDir, "missing.zip")); err == nil {
+		t.Error("Expected an error for a missing archive")
+	}
+}
+
+func TestDualPaneArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	writeZipFixture(t, path)
+
+	m, err := NewDualPaneModelWithRoots([]string{path}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.width, m.height, m.raw = 100, 20, true
+
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+	if len(m.allFiles) != 4 {
+		t.Fatalf("Expected 4 files in the archive, got %v", m.allFiles)
+	}
+	if m.loadedPath != filepath.Join(path, "README.md") || !strings.Contains(m.currentContent, "# Bundle") {
+		t.Errorf("Expected README.md from the archive to be shown, got %s: %q", m.loadedPath, m.currentContent)
+	}
+
+	// Links resolve within the archive
+	m.Update(m.loadGitStatus()())
+	m.followLink(Link{Target: "docs/guide.md"})
+	if m.loadedPath != filepath.Join(path, "docs", "guide.md") || !strings.Contains(m.currentContent, "Install it.") {
+		t.Errorf("Expected the link to open docs/guide.md, got %s: %q", m.loadedPath, m.currentContent)
+	}
+	if m.gitErr == nil {
+		t.Error("Expected no git status for an archive")
+	}
+
+	if _, err := NewDualPaneModelWithRoots([]string{path, "."}, FinderOptions{}); err == nil {
+		t.Error("Expected an error for an archive given with other paths")
+	}
+}
+
+func TestDualPaneArchiveNamedDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "v1.zip")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Release\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if isArchiveFile(dir) {
+		t.Error("Expected a directory not to be an archive")
+	}
+
+	m, err := NewDualPaneModelWithRoots([]string{dir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("Expected a directory named like an archive to be browsed, got %v", err)
+	}
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+	if len(m.allFiles) != 1 || m.fsys.archive != "" {
+		t.Errorf("Expected README.md from the directory, got %v", m.allFiles)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// config is the user's configuration file. Command line flags override it.
+type config struct {
+	// Extensions of markdown files, with or without the leading dot
+	Extensions []string `json:"extensions"`
+	// Patterns are globs for other file names to treat as markdown, such as
+	// extension-less README files
+	Patterns []string `json:"patterns"`
+	// Style is the rendering style: a builtin glamour style name or the
+	// path of a JSON stylesheet
+	Style string `json:"style"`
+}
+
+// configFilePath returns where the configuration is read from:
+// $XDG_CONFIG_HOME/md/config.json, defaulting to ~/.config.
+func configFilePath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "md", "config.json")
+}
+
+// loadConfig reads the configuration file. A missing file is an empty
+// configuration, but one that can't be parsed is an error so mistakes in it
+// don't go unnoticed.
+func loadConfig() (config, error) {
+	var cfg config
+
+	path := configFilePath()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	// No config file is not an error
+	cfg, err := loadConfig()
+	if err != nil || len(cfg.Extensions) != 0 {
+		t.Fatalf("Expected an empty config, got %+v, %v", cfg, err)
+	}
+
+	path := filepath.Join(configHome, "md", "config.json")
+	if path != configFilePath() {
+		t.Errorf("Expected config at %s, got %s", path, configFilePath())
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	data := `{"extensions": ["md", "txt"], "patterns": ["README"], "style": "light"}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if len(cfg.Extensions) != 2 || cfg.Extensions[1] != "txt" || len(cfg.Patterns) != 1 || cfg.Style != "light" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := loadConfig(); err == nil {
+		t.Error("Expected an error for an invalid config file")
+	}
+}
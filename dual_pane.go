@@ -2,26 +2,34 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/mattn/go-runewidth"
-	"sync"
-)
-
-var (
-	dualRendererCache = make(map[int]*glamour.TermRenderer)
-	dualRendererMutex sync.RWMutex
 )
 
 type DualPaneModel struct {
 	fileTree        *FileNode
 	allFiles        []string
-	treeLines       []string
+	treeRows        []treeRow
+	treeMessage     string          // Shown instead of the tree until it is loaded
+	collapsed       map[string]bool // Directories whose children are hidden, by path
+	showEmptyDirs   bool            // Show directories without markdown files
+	sortOrder       treeSort
+	filter          treeFilter
+	gitBase         string               // Branch to compare against for changed files
+	gitStatuses     map[string]gitStatus // Status of changed files by path
+	gitChanged      map[string]bool      // Files changed relative to HEAD or gitBase
+	gitErr          error
+	changedOnly     bool                // Show only changed files
+	fileInfo        map[string]fileInfo // Sort keys of files, read in the background
+	readingInfo     bool                // True while fileInfo is being read
 	selectedIndex   int
 	treeViewport    int
 	contentViewport int
@@ -33,11 +41,35 @@ type DualPaneModel struct {
 	renderer        *glamour.TermRenderer
 	focusedPane     int // 0 = tree, 1 = content
 	raw             bool
-	treeSelectedIdx int // Index of selected line in treeLines
-	includeIgnored  bool
+	hideMeta        bool   // Leave the front matter header out of the rendered view
+	style           string // Rendering style, see renderStyle
+	treeSelectedIdx int    // Index of selected row in treeRows
+	options         FinderOptions
+	fsys            treeFS // Where the tree's files are read from
 	rootPath        string
-	isExpanding     bool // True when background expansion is happening
-	currentDepth    int  // Current scan depth
+	roots           []string // Paths given on the command line; nil when browsing rootPath itself
+	scanning        bool     // True while files are still being discovered
+	walk            *treeWalker
+	walkPending     bool                 // Walk results not yet shown in the tree
+	walkScheduled   bool                 // A throttled rebuild with walk results is pending
+	walkID          int                  // Incremented for every walk, to drop stale results
+	scanID          int                  // Incremented for every scan, to drop stale results
+	gitStatusID     int                  // Incremented for every git status read, to drop stale results
+	dirNodes        map[string]*FileNode // Directory nodes of fileTree by path
+	search          searchState
+	projectSearch   projectSearchState
+	finder          fuzzyFinder
+	outline         picker
+	outlineLines    []int // Display line of each heading in the open outline
+	links           []Link
+	linkPicker      picker
+	history         navHistory
+	statusMessage   string // Transient message shown in the status bar
+	loadedPath      string // File shown in the content pane
+	loadedStamp     fileStamp
+	watching        bool   // True once the file watch loop is running
+	messageID       int    // Incremented whenever a timed message is shown
+	restoreFile     string // Remembered file to select once the scan finds it
 }
 
 func NewDualPaneModel(includeIgnored bool) (*DualPaneModel, error) {
@@ -45,27 +77,58 @@ func NewDualPaneModel(includeIgnored bool) (*DualPaneModel, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewDualPaneModelWithRoots([]string{cwd}, FinderOptions{IncludeIgnored: includeIgnored})
+}
+
+// NewDualPaneModelWithRoots browses the given files and directories. A single
+// directory or archive becomes the root of the tree; several paths are shown
+// side by side at the top level.
+func NewDualPaneModelWithRoots(paths []string, opts FinderOptions) (*DualPaneModel, error) {
+	var roots []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		if isArchiveFile(path) && len(paths) > 1 {
+			return nil, fmt.Errorf("%s: archives can only be browsed on their own", path)
+		}
+	}
+
+	var fsys treeFS
+	if isArchiveFile(paths[0]) {
+		var err error
+		if fsys, err = openArchive(paths[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	rootPath := absPath(paths[0])
+	if info, _ := os.Stat(rootPath); fsys.archive == "" && (len(paths) > 1 || !info.IsDir()) {
+		roots = paths
+		rootPath = commonDir(allAbsPaths(paths))
+	}
 
 	// Start with completely empty state for instant startup
 	emptyTree := &FileNode{
 		Name:  "Loading...",
-		Path:  cwd,
+		Path:  rootPath,
 		IsDir: true,
 	}
 
 	m := &DualPaneModel{
 		fileTree:        emptyTree,
 		allFiles:        []string{},
-		treeLines:       []string{"Loading markdown files..."},
+		treeMessage:     "Loading markdown files...",
 		selectedIndex:   0,
 		treeSelectedIdx: 0,
 		splitRatio:      0.3,
 		renderer:        nil, // Will be created lazily when needed
 		focusedPane:     0,
-		includeIgnored:  includeIgnored,
-		rootPath:        cwd,
-		currentDepth:    -1, // -1 indicates not started yet
-		isExpanding:     false,
+		style:           renderStyle,
+		options:         opts,
+		fsys:            fsys,
+		rootPath:        rootPath,
+		roots:           roots,
 	}
 
 	return m, nil
@@ -80,100 +143,238 @@ func (m *DualPaneModel) Init() tea.Cmd {
 
 type initialLoadMsg struct{}
 
+// loadCompleteMsg delivers the top level of the tree. id is the scan it
+// comes from, so that the results of a superseded scan can be dropped.
 type loadCompleteMsg struct {
+	id   int
 	tree *FileNode
 	err  error
 }
 
-type expandTreeMsg struct{}
+// scan lists the top level of the tree in the background. The rest is
+// discovered by a walker once that is shown.
+func (m *DualPaneModel) scan() tea.Cmd {
+	m.scanning = true
+	m.walk.stop()
+	m.walk = nil
+	m.scanID++
+
+	// The scan runs outside Update, so it gets its own copy of the tree's
+	// settings
+	id, fsys, rootPath, roots, opts := m.scanID, m.fsys, m.rootPath, slices.Clone(m.roots), m.options
+	return func() tea.Msg {
+		fileTree, err := scanTree(fsys, rootPath, roots, opts, 0)
+		if err != nil {
+			return loadCompleteMsg{id: id, err: err}
+		}
+		return loadCompleteMsg{id: id, tree: fileTree}
+	}
+}
+
+// rescan scans the tree again, keeping the shown file.
+func (m *DualPaneModel) rescan() tea.Cmd {
+	if m.restoreFile == "" {
+		m.restoreFile = m.loadedPath
+	}
+	return m.scan()
+}
 
-func (m *DualPaneModel) expandTree() tea.Cmd {
-	if m.isExpanding {
-		return nil
+// startWalk discovers the files below the top level of the tree.
+func (m *DualPaneModel) startWalk() tea.Cmd {
+	m.walk.stop()
+	roots := []string{m.rootPath}
+	if m.roots != nil {
+		roots = allAbsPaths(m.roots)
 	}
 
-	return tea.Tick(time.Millisecond*50, func(t time.Time) tea.Msg {
-		return performExpansionMsg{}
-	})
+	m.walkID++
+	m.walk = startWalk(m.walkID, m.fsys, roots, m.options)
+	return m.walk.next()
 }
 
-type performExpansionMsg struct{}
+// addWalkEntries inserts newly discovered entries into the tree. It reports
+// whether any of them were new.
+func (m *DualPaneModel) addWalkEntries(entries []walkEntry) bool {
+	added := false
+	for _, entry := range entries {
+		parent := m.dirNodes[filepath.Dir(entry.path)]
+		if parent == nil {
+			continue
+		}
+		node := &FileNode{Name: filepath.Base(entry.path), Path: entry.path, IsDir: entry.isDir}
+		if !insertNode(parent, node) {
+			continue
+		}
+		if node.IsDir {
+			m.dirNodes[node.Path] = node
+		}
+		added = true
+	}
+	return added
+}
+
+// applyWalk shows the files the walk found since the tree was last rebuilt.
+func (m *DualPaneModel) applyWalk() {
+	if !m.walkPending {
+		return
+	}
+	m.walkPending = false
+	m.allFiles = CollectFiles(m.fileTree)
+	m.rebuildTree()
+
+	// New files may have shifted the index of the shown one
+	if index := m.fileIndex(m.loadedPath); index >= 0 {
+		m.selectedIndex = index
+	} else if len(m.allFiles) > 0 && m.loadedPath == "" && m.restoreFile == "" {
+		m.selectFile(0) // The top level had no files
+	}
+
+	// The remembered file may be deeper than the first scan
+	if index := m.fileIndex(m.restoreFile); index >= 0 {
+		m.restoreFile = ""
+		m.selectFile(index)
+	}
+}
 
 func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case initialLoadMsg:
 		// Perform initial load of depth 0 files
-		if m.currentDepth == -1 {
-			m.currentDepth = 0
-			m.isExpanding = true
-
-			// Run in background to avoid blocking UI
-			return m, func() tea.Msg {
-				fileTree, err := FindMarkdownFilesQuick(m.rootPath, m.includeIgnored)
-				if err != nil {
-					return loadCompleteMsg{err: err}
-				}
-				return loadCompleteMsg{tree: fileTree, err: nil}
-			}
+		if !m.scanning && m.dirNodes == nil {
+			return m, m.scan()
 		}
 		return m, nil
 
 	case loadCompleteMsg:
+		if msg.id != m.scanID {
+			return m, nil
+		}
 		if msg.err != nil {
-			m.treeLines = []string{"Error loading files: " + msg.err.Error()}
-			m.isExpanding = false
+			m.treeRows = nil
+			m.treeMessage = "Error loading files: " + msg.err.Error()
+			m.scanning = false
 			return m, nil
 		}
 
+		firstLoad := m.dirNodes == nil
+		m.treeMessage = ""
+		m.walkPending = false
 		m.fileTree = msg.tree
 		m.allFiles = CollectFiles(msg.tree)
-		m.treeLines = FlattenTree(msg.tree, "", false)
-		m.isExpanding = false
+		m.dirNodes = make(map[string]*FileNode)
+		indexDirs(m.fileTree, m.dirNodes)
+		m.rebuildTree()
+
+		// Resume the layout and file of the last session in this directory
+		if state, ok := lookupTreeState(m.treeStateKey()); ok && firstLoad {
+			if state.SplitRatio >= 0.2 && state.SplitRatio <= 0.5 {
+				m.splitRatio = state.SplitRatio
+				if m.width > 0 {
+					m.updateRendererWidth()
+				}
+			}
+			m.restoreFile = state.LastFile
+		}
+
+		// Load the remembered file, or the first file if available
+		if index := m.fileIndex(m.restoreFile); index >= 0 {
+			m.restoreFile = ""
+			m.selectFile(index)
+		} else if len(m.allFiles) > 0 && firstLoad {
+			m.selectFile(0)
+		} else {
+			m.selectedIndex = m.fileIndex(m.loadedPath)
+		}
 
-		// Load first file if available
-		if len(m.allFiles) > 0 {
-			m.selectedIndex = 0
-			m.treeSelectedIdx = findTreeLineForFile(0, m.treeLines, m.allFiles)
-			m.loadFile(0)
+		// Watch the displayed file for changes from now on; files in an
+		// archive don't change
+		var watch tea.Cmd
+		if !m.watching && m.fsys.archive == "" {
+			m.watching = true
+			watch = watchFile(m.loadedPath, m.loadedStamp)
 		}
 
-		// Start background expansion to deeper levels
-		return m, tea.Tick(time.Millisecond*500, func(t time.Time) tea.Msg {
-			return expandTreeMsg{}
-		})
+		// Discover the rest of the tree in the background
+		return m, tea.Batch(watch, m.startWalk(), m.readFileInfo(), m.loadGitStatus())
 
-	case expandTreeMsg:
-		return m, m.expandTree()
-
-	case performExpansionMsg:
-		if !m.isExpanding {
-			m.isExpanding = true
-			// Expand to next depth level
-			m.currentDepth++
-			newTree, err := FindMarkdownFilesWithDepth(m.rootPath, m.includeIgnored, m.currentDepth)
-			if err == nil {
-				newFiles := CollectFiles(newTree)
-				if len(newFiles) > len(m.allFiles) {
-					// We found new files, update the model
-					m.fileTree = newTree
-					m.allFiles = newFiles
-					m.treeLines = FlattenTree(newTree, "", false)
-
-					// Preserve selection if possible
-					if m.selectedIndex < len(m.allFiles) {
-						m.treeSelectedIdx = findTreeLineForFile(m.selectedIndex, m.treeLines, m.allFiles)
-					}
-				}
+	case fileWatchMsg:
+		var cmd tea.Cmd
+		if msg.path != "" && msg.path == m.loadedPath {
+			if msg.changed && msg.content != m.currentContent {
+				cmd = m.reload(msg.content)
 			}
-			m.isExpanding = false
+			m.loadedStamp = msg.stamp
+		}
+		return m, tea.Batch(cmd, watchFile(m.loadedPath, m.loadedStamp))
 
-			// Continue expanding if we haven't hit a reasonable depth limit
-			if m.currentDepth < 5 {
-				return m, m.expandTree()
-			}
+	case clearStatusMsg:
+		if msg.id == m.messageID {
+			m.statusMessage = ""
+		}
+		return m, nil
+
+	case walkBatchMsg:
+		if m.walk == nil || msg.id != m.walk.id {
+			return m, nil // Results of a cancelled walk
+		}
+
+		if m.addWalkEntries(msg.entries) {
+			m.walkPending = true
+		}
+
+		if msg.done {
+			m.walk = nil
+			m.scanning = false
+			m.applyWalk()
+			return m, m.readFileInfo()
+		}
+		if !m.walkPending || m.walkScheduled {
+			return m, m.walk.next()
+		}
+		m.walkScheduled = true
+		rebuild := tea.Tick(walkRebuildInterval, func(time.Time) tea.Msg {
+			return walkRebuildMsg{}
+		})
+		return m, tea.Batch(m.walk.next(), rebuild)
+
+	case walkRebuildMsg:
+		m.walkScheduled = false
+		if !m.walkPending {
+			return m, nil // Shown when the walk finished or the tree was rescanned
+		}
+		m.applyWalk()
+		return m, m.readFileInfo()
+
+	case gitStatusMsg:
+		if msg.id != m.gitStatusID {
+			return m, nil // Superseded by a later read
+		}
+		m.gitStatuses, m.gitChanged, m.gitErr = msg.statuses, msg.changed, msg.err
+		if msg.err != nil && m.changedOnly {
+			// Asked for with -base, but there is nothing to compare
+			m.changedOnly = false
+			m.rebuildTree()
+			return m, m.flash("No git status: " + msg.err.Error())
+		}
+		m.rebuildTree()
+		if m.changedOnly {
+			m.selectVisibleFile()
 		}
 		return m, nil
 
+	case fileInfoMsg:
+		if m.fileInfo == nil {
+			m.fileInfo = make(map[string]fileInfo)
+		}
+		for path, info := range msg.info {
+			m.fileInfo[path] = info
+		}
+		m.readingInfo = false
+		m.rebuildTree()
+
+		// Files found in the meantime
+		return m, m.readFileInfo()
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height - 2 // Reserve space for status bar
@@ -181,11 +382,116 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Update renderer width based on content pane width
 		m.updateRendererWidth()
 
+	case projectSearchDoneMsg:
+		if msg.id == m.projectSearch.id {
+			m.projectSearch.running = false
+			m.projectSearch.results = msg.results
+			m.projectSearch.err = msg.err
+			m.projectSearch.selected = 0
+			m.projectSearch.viewport = 0
+		}
+		return m, nil
+
 	case tea.KeyMsg:
+		m.statusMessage = ""
+		m.restoreFile = "" // Don't move the selection once the user has taken over
+		if m.linkPicker.active {
+			_, height := m.popupSize(len(m.linkPicker.items))
+			if index := m.linkPicker.handleKey(msg, m.linkPicker.visibleRows(height)); index >= 0 {
+				m.followLink(m.links[index])
+			}
+			return m, nil
+		}
+		if m.finder.active {
+			_, height := m.finderSize()
+			if path := m.finder.handleKey(msg, m.finder.visibleRows(height)); path != "" {
+				// The walk may have moved or removed the file since the
+				// finder opened; selectFile ignores -1
+				m.selectFile(m.fileIndex(path))
+			}
+			return m, nil
+		}
+		if m.outline.active {
+			_, height := m.popupSize(len(m.outline.items))
+			if index := m.outline.handleKey(msg, m.outline.visibleRows(height)); index >= 0 {
+				m.scrollContentTo(m.outlineLines[index])
+				m.focusedPane = 1
+			}
+			return m, nil
+		}
+		if m.search.typing {
+			m.updateSearchInput(msg)
+			return m, nil
+		}
+		if m.projectSearch.typing {
+			return m, m.updateProjectSearchInput(msg)
+		}
+		if m.filter.typing {
+			m.updateFilterInput(msg)
+			return m, nil
+		}
+		if m.projectSearch.active && m.focusedPane == 0 && m.updateProjectSearchResults(msg) {
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
+			m.walk.stop()
+			m.rememberPosition()
+			rememberTreeState(m.treeStateKey(), m.loadedPath, m.splitRatio)
 			return m, tea.Quit
 
+		case "esc":
+			if m.focusedPane == 0 && m.filter.active() {
+				m.clearFilter()
+			} else {
+				m.search.clear()
+			}
+
+		case "/", "?":
+			if m.focusedPane == 1 {
+				m.search.begin(msg.String() == "?", m.contentViewport)
+			} else if msg.String() == "/" {
+				m.beginFilter()
+			}
+
+		case "o":
+			// Show the outline of the current document
+			headings := ParseHeadings(m.currentContent)
+			m.outlineLines = headingDisplayLines(headings, m.renderedLines, m.raw, strings.Count(m.currentContent, "\n")+1)
+			m.outline.open("Outline", outlineItems(headings), currentHeading(m.outlineLines, m.contentViewport))
+
+		case "f":
+			// Pick a link to follow
+			m.links = InternalLinks(m.currentContent)
+			m.linkPicker.open("Links", linkItems(m.links), 0)
+
+		case "[", "backspace":
+			if entry, ok := m.history.back(m.historyEntry()); ok {
+				m.openHistoryEntry(entry)
+			}
+
+		case "]":
+			if entry, ok := m.history.forward(m.historyEntry()); ok {
+				m.openHistoryEntry(entry)
+			}
+
+		case "ctrl+p":
+			// Fuzzy find a file by its path
+			m.finder.open(m.allFiles, m.rootPath)
+
+		case "ctrl+f":
+			// Search across all files; results replace the tree pane
+			m.projectSearch.active = true
+			m.projectSearch.typing = true
+
+		case "n", "N":
+			if m.focusedPane == 1 {
+				if line, ok := m.search.next(m.contentViewport, msg.String() == "N"); ok {
+					m.scrollContentTo(line)
+				}
+			}
+
 		case "tab":
 			// Switch focus between panes
 			m.focusedPane = (m.focusedPane + 1) % 2
@@ -193,21 +499,35 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "h", "left":
 			if m.focusedPane == 1 {
 				m.focusedPane = 0
+			} else if node := m.cursorNode(); node != nil && node.IsDir && !m.collapsed[node.Path] {
+				m.setCollapsed(node.Path, true)
+			} else if node != nil {
+				// Move up to the enclosing directory
+				if line := m.treeLineForPath(filepath.Dir(node.Path)); line >= 0 {
+					m.moveTreeCursor(line)
+				}
 			}
 
 		case "l", "right":
 			if m.focusedPane == 0 {
-				m.focusedPane = 1
+				if node := m.cursorNode(); node != nil && node.IsDir && m.collapsed[node.Path] {
+					m.setCollapsed(node.Path, false)
+				} else {
+					m.focusedPane = 1
+				}
+			}
+
+		case "s", "S", "d", "c", "E", "+", "-", ".":
+			// Keys that change the tree only apply while it has focus
+			if m.focusedPane == 0 {
+				return m, m.treeKey(msg.String())
 			}
 
 		case "j", "down":
 			if m.focusedPane == 0 {
 				// Tree navigation
-				if m.selectedIndex < len(m.allFiles)-1 {
-					m.selectedIndex++
-					m.treeSelectedIdx = findTreeLineForFile(m.selectedIndex, m.treeLines, m.allFiles)
-					m.loadFile(m.selectedIndex)
-					m.adjustTreeViewport()
+				if m.treeSelectedIdx < len(m.treeRows)-1 {
+					m.moveTreeCursor(m.treeSelectedIdx + 1)
 				}
 			} else {
 				// Content scrolling
@@ -220,11 +540,8 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "k", "up":
 			if m.focusedPane == 0 {
 				// Tree navigation
-				if m.selectedIndex > 0 {
-					m.selectedIndex--
-					m.treeSelectedIdx = findTreeLineForFile(m.selectedIndex, m.treeLines, m.allFiles)
-					m.loadFile(m.selectedIndex)
-					m.adjustTreeViewport()
+				if m.treeSelectedIdx > 0 {
+					m.moveTreeCursor(m.treeSelectedIdx - 1)
 				}
 			} else {
 				// Content scrolling
@@ -234,9 +551,12 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter":
-			if m.focusedPane == 0 && m.selectedIndex >= 0 && m.selectedIndex < len(m.allFiles) {
-				m.focusedPane = 1
-				m.contentViewport = 0
+			if m.focusedPane == 0 {
+				if node := m.cursorNode(); node != nil && node.IsDir {
+					m.setCollapsed(node.Path, !m.collapsed[node.Path])
+				} else if m.selectedIndex >= 0 && m.selectedIndex < len(m.allFiles) {
+					m.focusedPane = 1
+				}
 			}
 
 		case "ctrl+d", "pgdown":
@@ -260,24 +580,16 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "g", "home":
 			if m.focusedPane == 1 {
 				m.contentViewport = 0
-			} else {
-				m.selectedIndex = 0
-				m.treeSelectedIdx = findTreeLineForFile(0, m.treeLines, m.allFiles)
-				m.treeViewport = 0
-				if len(m.allFiles) > 0 {
-					m.loadFile(0)
-				}
+			} else if len(m.treeRows) > 0 {
+				m.moveTreeCursor(0)
 			}
 
 		case "G", "end":
 			if m.focusedPane == 1 {
 				availableHeight := m.height - 2
 				m.contentViewport = max(0, len(m.renderedLines)-availableHeight)
-			} else {
-				m.selectedIndex = len(m.allFiles) - 1
-				m.treeSelectedIdx = findTreeLineForFile(m.selectedIndex, m.treeLines, m.allFiles)
-				m.loadFile(m.selectedIndex)
-				m.adjustTreeViewport()
+			} else if len(m.treeRows) > 0 {
+				m.moveTreeCursor(len(m.treeRows) - 1)
 			}
 
 		case "r":
@@ -285,6 +597,20 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.raw = !m.raw
 			m.refreshContent()
 
+		case "m":
+			// Toggle the front matter header
+			m.hideMeta = !m.hideMeta
+			m.refreshContent()
+			m.scrollContentTo(m.contentViewport)
+			return m, m.flash(metaMessage(m.hideMeta))
+
+		case "t":
+			// Cycle rendering styles
+			m.style = nextStyle(m.style)
+			m.renderer = nil
+			m.updateRendererWidth()
+			return m, m.flash("Style: " + styleName(m.style))
+
 		case "<", "{":
 			// Decrease split ratio
 			m.splitRatio = maxFloat(0.2, m.splitRatio-0.05)
@@ -295,11 +621,10 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.splitRatio = minFloat(0.5, m.splitRatio+0.05)
 			m.updateRendererWidth()
 
-		case "e":
-			// Manual expand - scan deeper
-			if !m.isExpanding {
-				return m, m.expandTree()
-			}
+		case "R":
+			// Rescan from scratch, keeping the shown file and folded
+			// directories
+			return m, m.rescan()
 		}
 
 	case tea.MouseMsg:
@@ -311,7 +636,7 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Mouse is in tree pane - scroll tree
 				if msg.Type == tea.MouseWheelUp && m.treeViewport > 0 {
 					m.treeViewport--
-				} else if msg.Type == tea.MouseWheelDown && m.treeViewport < len(m.treeLines)-(m.height-2) {
+				} else if msg.Type == tea.MouseWheelDown && m.treeViewport < len(m.treeRows)-(m.height-2) {
 					m.treeViewport++
 				}
 			} else {
@@ -329,6 +654,60 @@ func (m *DualPaneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// treeKey applies a key that changes how the tree is sorted, filtered or
+// folded.
+func (m *DualPaneModel) treeKey(key string) tea.Cmd {
+	switch key {
+	case "s":
+		// Cycle through the sort modes
+		order := m.sortOrder
+		order.mode = (order.mode + 1) % sortModeCount
+		return m.setSortOrder(order)
+
+	case "S":
+		order := m.sortOrder
+		order.reverse = !order.reverse
+		return m.setSortOrder(order)
+
+	case "d":
+		// Toggle directories first
+		order := m.sortOrder
+		order.mixed = !order.mixed
+		return m.setSortOrder(order)
+
+	case "c":
+		return m.toggleChangedOnly()
+
+	case "E":
+		// Toggle directories without markdown files
+		m.showEmptyDirs = !m.showEmptyDirs
+		m.rebuildTree()
+
+	case "+":
+		// Expand all directories
+		m.collapsed = nil
+		m.rebuildTree()
+
+	case "-":
+		// Collapse all directories
+		m.collapsed = make(map[string]bool)
+		for _, dir := range CollectDirs(m.fileTree) {
+			m.collapsed[dir] = true
+		}
+		m.rebuildTree()
+
+	case ".":
+		// Toggle hidden files
+		m.options.IncludeHidden = !m.options.IncludeHidden
+		message := "Hiding dotfiles"
+		if m.options.IncludeHidden {
+			message = "Showing dotfiles"
+		}
+		return tea.Batch(m.rescan(), m.flash(message))
+	}
+	return nil
+}
+
 func (m *DualPaneModel) View() string {
 	if m.height == 0 {
 		return "Loading..."
@@ -339,11 +718,11 @@ func (m *DualPaneModel) View() string {
 
 	// Styles
 	focusedStyle := lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
+		BorderStyle(glyphs.focusedBorder).
 		BorderForeground(lipgloss.Color("62"))
 
 	unfocusedStyle := lipgloss.NewStyle().
-		BorderStyle(lipgloss.NormalBorder()).
+		BorderStyle(glyphs.border).
 		BorderForeground(lipgloss.Color("240"))
 
 	selectedStyle := lipgloss.NewStyle().
@@ -361,41 +740,40 @@ func (m *DualPaneModel) View() string {
 
 	// Build tree view
 	var treeContent strings.Builder
-	for i := 0; i < availableHeight; i++ {
-		lineIdx := m.treeViewport + i
-		if lineIdx < len(m.treeLines) {
-			line := m.treeLines[lineIdx]
-
-			// Check if this is the selected line
-			isSelected := (lineIdx == m.treeSelectedIdx)
-
-			// Add cursor prefix for selected line
-			var displayLine string
-			if isSelected && m.focusedPane == 0 {
-				displayLine = "\u276f " + line
-			} else {
-				displayLine = "  " + line
-			}
+	if m.projectSearch.active {
+		treeContent.WriteString(m.projectSearchView(treeWidth-4, availableHeight))
+	} else if len(m.treeRows) == 0 {
+		treeContent.WriteString(truncateToWidth("  "+m.emptyTreeMessage(), treeWidth-4))
+	} else {
+		for i := 0; i < availableHeight; i++ {
+			lineIdx := m.treeViewport + i
+			if lineIdx < len(m.treeRows) {
+				line := m.treeRows[lineIdx].text()
+
+				// Check if this is the selected line
+				isSelected := (lineIdx == m.treeSelectedIdx)
 
-			// Truncate line to fit width using proper character width
-			maxWidth := treeWidth - 4 // Account for border padding
-			if maxWidth > 0 && runewidth.StringWidth(displayLine) > maxWidth {
-				if maxWidth > 3 {
-					displayLine = runewidth.Truncate(displayLine, maxWidth-3, "...")
+				// Add cursor prefix for selected line
+				var displayLine string
+				if isSelected && m.focusedPane == 0 {
+					displayLine = glyphs.cursor + " " + line
 				} else {
-					displayLine = runewidth.Truncate(displayLine, maxWidth, "")
+					displayLine = "  " + line
 				}
-			}
 
-			// Apply background highlight for selected item
-			if isSelected && m.focusedPane == 0 {
-				displayLine = selectedStyle.Render(displayLine)
-			}
+				// Truncate line to fit width using proper character width
+				displayLine = truncateToWidth(displayLine, treeWidth-4) // Account for border padding
 
-			treeContent.WriteString(displayLine)
-		}
-		if i < availableHeight-1 {
-			treeContent.WriteString("\n")
+				// Apply background highlight for selected item
+				if isSelected && m.focusedPane == 0 {
+					displayLine = selectedStyle.Render(displayLine)
+				}
+
+				treeContent.WriteString(displayLine)
+			}
+			if i < availableHeight-1 {
+				treeContent.WriteString("\n")
+			}
 		}
 	}
 
@@ -404,7 +782,7 @@ func (m *DualPaneModel) View() string {
 	endLine := min(m.contentViewport+availableHeight, len(m.renderedLines))
 
 	for i := m.contentViewport; i < endLine; i++ {
-		line := m.renderedLines[i]
+		line := m.search.highlight(m.renderedLines[i])
 		// Don't truncate content lines - let them wrap naturally
 		// The renderer should handle word wrapping
 		contentView.WriteString(line)
@@ -434,9 +812,9 @@ func (m *DualPaneModel) View() string {
 
 		for i := 0; i < scrollBarHeight; i++ {
 			if i >= thumbPosition && i < thumbPosition+thumbHeight {
-				scrollBar.WriteString("█") // Solid block for thumb
+				scrollBar.WriteString(glyphs.scrollThumb)
 			} else {
-				scrollBar.WriteString("░") // Light shade for track
+				scrollBar.WriteString(glyphs.scrollTrack)
 			}
 			if i < scrollBarHeight-1 {
 				scrollBar.WriteString("\n")
@@ -445,7 +823,7 @@ func (m *DualPaneModel) View() string {
 	} else {
 		// Empty scroll bar
 		for i := 0; i < availableHeight; i++ {
-			scrollBar.WriteString("░")
+			scrollBar.WriteString(glyphs.scrollTrack)
 			if i < availableHeight-1 {
 				scrollBar.WriteString("\n")
 			}
@@ -500,47 +878,407 @@ func (m *DualPaneModel) View() string {
 
 	// Add expansion indicator
 	expansionStatus := ""
-	if m.currentDepth == -1 {
-		expansionStatus = " | Initializing..."
-	} else if m.isExpanding {
-		expansionStatus = " | Scanning..."
-	} else if m.currentDepth > 0 {
-		expansionStatus = fmt.Sprintf(" | Depth %d", m.currentDepth)
+	if m.scanning {
+		expansionStatus = fmt.Sprintf(" | Scanning... %d files", len(m.allFiles))
 	}
 
-	status := fmt.Sprintf("* %s | %s | Focus: %s%s | [tab]switch [e]xpand [q]uit [r]aw/render [<>]resize",
+	filterStatus := ""
+	if m.changedOnly {
+		filterStatus = " | Changed files"
+	}
+	if m.filter.input != "" && !m.filter.typing {
+		filterStatus += fmt.Sprintf(" | Filter: %s (%d files)", m.filter.input, m.visibleFileCount())
+	}
+
+	status := fmt.Sprintf("* %s | %s | Focus: %s%s%s | [tab]switch [R]escan [+-]fold [q]uit [r]aw/render [t]heme [m]eta [<>]resize [/]search [^f]find in files [^p]go to file [o]utline [f]ollow link",
 		currentFile,
 		viewMode,
 		focusIndicator,
 		expansionStatus,
+		filterStatus,
 	)
 
-	return mainView + "\n" + statusStyle.Render(status)
+	// Search prompts replace the status bar while typing; otherwise the match
+	// count is appended to it
+	if m.projectSearch.typing {
+		mode := "literal"
+		if m.projectSearch.regex {
+			mode = "regex"
+		}
+		status = fmt.Sprintf("Search files (%s, ctrl+r toggles): %s", mode, m.projectSearch.input)
+	} else if m.filter.typing {
+		status = fmt.Sprintf("Filter (substring or glob): %s", m.filter.input)
+	} else if m.search.typing {
+		status = m.search.status()
+	} else if searchStatus := m.search.status(); searchStatus != "" {
+		status = fmt.Sprintf("* %s | %s", currentFile, searchStatus)
+	}
+
+	if m.statusMessage != "" {
+		status = fmt.Sprintf("* %s | %s", currentFile, m.statusMessage)
+	}
+
+	// Keep the status bar on a single line
+	status = truncateToWidth(status, m.width-2)
+
+	view := mainView + "\n" + statusStyle.Render(status)
+	if m.finder.active {
+		width, height := m.finderSize()
+		view = centerOverlay(m.finder.view(width, height), view, m.width, m.height+2)
+	}
+	for _, popup := range []*picker{&m.outline, &m.linkPicker} {
+		if popup.active {
+			width, height := m.popupSize(len(popup.items))
+			view = centerOverlay(popup.view(width, height), view, m.width, m.height+2)
+		}
+	}
+	return view
 }
 
-func (m *DualPaneModel) ensureRenderer() {
-	if m.renderer == nil {
-		width := 60 // Default width
+// scanTree finds the markdown files below rootPath, or among roots if they
+// are given, down to maxDepth. Depth 0 is the quick scan of the top level
+// only.
+func scanTree(fsys treeFS, rootPath string, roots []string, opts FinderOptions, maxDepth int) (*FileNode, error) {
+	if roots != nil {
+		return FindMarkdownFilesInPaths(roots, opts, maxDepth)
+	}
+	return fsys.findMarkdownFiles(rootPath, opts, maxDepth)
+}
 
-		// Check cache first
-		dualRendererMutex.RLock()
-		if cached, exists := dualRendererCache[width]; exists {
-			m.renderer = cached
-			dualRendererMutex.RUnlock()
+// treeStateKey identifies the tree for remembering its layout: the root
+// directory, or the list of paths when several were given.
+func (m *DualPaneModel) treeStateKey() string {
+	if m.roots == nil {
+		return m.rootPath
+	}
+	return strings.Join(allAbsPaths(m.roots), string(filepath.ListSeparator))
+}
+
+// popupSize returns the dimensions of a popup such as the outline listing
+// the given number of items.
+func (m *DualPaneModel) popupSize(items int) (int, int) {
+	return min(60, max(20, m.width*2/3)), max(5, min(m.height-2, items+3))
+}
+
+// currentFile returns the path of the file shown in the content pane, or ""
+// if there is none.
+func (m *DualPaneModel) currentFile() string {
+	if m.selectedIndex >= 0 && m.selectedIndex < len(m.allFiles) {
+		return m.allFiles[m.selectedIndex]
+	}
+	return ""
+}
+
+// fileIndex returns the index of path in allFiles, or -1.
+func (m *DualPaneModel) fileIndex(path string) int {
+	path = filepath.Clean(path)
+	for i, file := range m.allFiles {
+		if filepath.Clean(file) == path {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *DualPaneModel) historyEntry() historyEntry {
+	return historyEntry{path: m.currentFile(), viewport: m.contentViewport}
+}
+
+// followLink opens the target of a link in the content pane and moves the
+// tree selection to it, pushing the current position onto the history.
+func (m *DualPaneModel) followLink(link Link) {
+	path, anchor := resolveLink(m.fsys, m.currentFile(), link.Target)
+	index := m.selectedIndex
+	if path != "" {
+		if index = m.fileIndex(path); index < 0 {
+			m.statusMessage = "Link target not in tree: " + link.Target
+			return
+		}
+	}
+
+	m.history.visit(m.historyEntry())
+	if index != m.selectedIndex {
+		m.selectFile(index)
+	}
+	m.focusedPane = 1
+	if anchor == "" {
+		m.scrollContentTo(0)
+		return
+	}
+	if line, ok := anchorLine(m.currentContent, m.renderedLines, m.raw, anchor); ok {
+		m.scrollContentTo(line)
+	} else {
+		m.statusMessage = "Anchor not found: #" + anchor
+	}
+}
+
+// openHistoryEntry returns to a position from the history.
+func (m *DualPaneModel) openHistoryEntry(entry historyEntry) {
+	index := m.fileIndex(entry.path)
+	if index < 0 {
+		m.statusMessage = "File no longer in tree: " + entry.path
+		return
+	}
+	if index != m.selectedIndex {
+		m.selectFile(index)
+	}
+	m.scrollContentTo(entry.viewport)
+}
+
+// finderSize returns the dimensions of the fuzzy finder popup.
+func (m *DualPaneModel) finderSize() (int, int) {
+	return min(80, max(20, m.width*2/3)), min(20, max(6, m.height*2/3))
+}
+
+// selectFile moves the tree selection to allFiles[index] and loads it.
+func (m *DualPaneModel) selectFile(index int) {
+	if index < 0 || index >= len(m.allFiles) {
+		return
+	}
+	m.selectedIndex = index
+	m.revealInTree(m.allFiles[index])
+	m.loadFile(index)
+}
+
+// cursorNode returns the tree node under the cursor, or nil.
+func (m *DualPaneModel) cursorNode() *FileNode {
+	if m.treeSelectedIdx >= 0 && m.treeSelectedIdx < len(m.treeRows) {
+		return m.treeRows[m.treeSelectedIdx].node
+	}
+	return nil
+}
+
+// treeLineForPath returns the tree line showing path, or -1 if it isn't
+// visible.
+func (m *DualPaneModel) treeLineForPath(path string) int {
+	path = filepath.Clean(path)
+	for i, row := range m.treeRows {
+		if row.node.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveTreeCursor puts the tree cursor on line and shows the file there, if
+// it is a file.
+func (m *DualPaneModel) moveTreeCursor(line int) {
+	m.treeSelectedIdx = line
+	m.adjustTreeViewport()
+
+	node := m.cursorNode()
+	if node == nil || node.IsDir || node.Path == m.loadedPath {
+		return
+	}
+	if index := m.fileIndex(node.Path); index >= 0 {
+		m.selectedIndex = index
+		m.loadFile(index)
+	}
+}
+
+// rebuildTree re-renders the tree lines after the tree or the collapsed
+// directories changed. The cursor stays on the same node or, if that is now
+// hidden, moves to the nearest visible directory above it.
+func (m *DualPaneModel) rebuildTree() {
+	current := ""
+	if node := m.cursorNode(); node != nil {
+		current = node.Path
+	}
+
+	m.treeRows = m.visibleRows()
+	m.treeSelectedIdx = 0
+	for path := current; path != ""; path = filepath.Dir(path) {
+		if line := m.treeLineForPath(path); line >= 0 {
+			m.treeSelectedIdx = line
+			break
+		}
+		if path == filepath.Dir(path) {
+			break
+		}
+	}
+	m.adjustTreeViewport()
+}
+
+// visibleRows returns the rows of the visible part of the tree. Directories without
+// markdown files are left out unless they were asked for; since this is
+// decided on every rebuild, directories appear as soon as the scan finds
+// files in them.
+func (m *DualPaneModel) visibleRows() []treeRow {
+	tree := m.fileTree
+	if !m.showEmptyDirs {
+		tree = PruneEmptyDirs(tree)
+	}
+	if m.filter.match != nil {
+		tree = filterTree(tree, m.rootPath, m.filter.match)
+	}
+	if m.changedOnly && m.gitChanged != nil {
+		tree = filterTree(tree, m.rootPath, func(path string) bool {
+			return m.gitChanged[filepath.Join(m.rootPath, filepath.FromSlash(path))]
+		})
+	}
+	tree = sortedTree(tree, m.sortOrder, m.fileInfo)
+
+	rows := treeRows(tree, m.collapsed)
+	for i, row := range rows {
+		if !row.node.IsDir {
+			rows[i].marker = m.gitStatuses[row.node.Path].marker()
+		}
+	}
+	return rows
+}
+
+// emptyTreeMessage explains why the tree pane has no rows.
+func (m *DualPaneModel) emptyTreeMessage() string {
+	switch {
+	case m.treeMessage != "":
+		return m.treeMessage
+	case m.filter.active() || m.changedOnly:
+		return "No matching files"
+	case m.scanning:
+		return "Loading markdown files..."
+	}
+	return "No markdown files found"
+}
+
+// selectVisibleFile keeps the tree cursor on a visible file after the tree
+// was narrowed down: the one shown if it is still there, otherwise the
+// first one.
+func (m *DualPaneModel) selectVisibleFile() {
+	if line := m.treeLineForPath(m.loadedPath); line >= 0 {
+		m.treeSelectedIdx = line
+		m.adjustTreeViewport()
+		return
+	}
+	for line, row := range m.treeRows {
+		if !row.node.IsDir {
+			m.moveTreeCursor(line)
 			return
 		}
-		dualRendererMutex.RUnlock()
+	}
+}
+
+// toggleChangedOnly switches between all files and just the ones git
+// reports as changed.
+func (m *DualPaneModel) toggleChangedOnly() tea.Cmd {
+	if m.gitChanged == nil {
+		if m.gitErr != nil {
+			return m.flash("No git status: " + m.gitErr.Error())
+		}
+		return m.flash("Git status not loaded yet")
+	}
 
-		renderer, err := glamour.NewTermRenderer(
-			glamour.WithStandardStyle("dark"),
-			glamour.WithWordWrap(width),
-		)
-		if err == nil {
+	m.changedOnly = !m.changedOnly
+	m.rebuildTree()
+	if !m.changedOnly {
+		return m.flash("Showing all files")
+	}
+
+	m.selectVisibleFile()
+	if len(m.treeRows) == 0 {
+		return m.flash("No changed files")
+	}
+	if m.gitBase != "" {
+		return m.flash("Showing files changed since " + m.gitBase)
+	}
+	return m.flash("Showing changed files")
+}
+
+// loadGitStatus reads the git status of the tree's files in the background,
+// from every repository its paths are in.
+func (m *DualPaneModel) loadGitStatus() tea.Cmd {
+	m.gitStatusID++
+	id := m.gitStatusID
+	if m.fsys.archive != "" {
+		archive := filepath.Base(m.fsys.archive)
+		return func() tea.Msg {
+			return gitStatusMsg{id: id, err: fmt.Errorf("%s is an archive", archive)}
+		}
+	}
+	roots := []string{m.rootPath}
+	if m.roots != nil {
+		roots = allAbsPaths(m.roots)
+	}
+	return loadGitStatus(id, roots, m.gitBase)
+}
+
+// readFileInfo reads the sort keys of files that don't have them yet, if
+// the sort order needs them.
+func (m *DualPaneModel) readFileInfo() tea.Cmd {
+	if !m.sortOrder.needsInfo() || m.readingInfo {
+		return nil
+	}
+
+	withTitles := m.sortOrder.mode == sortTitle
+	var missing []string
+	for _, path := range m.allFiles {
+		if info, ok := m.fileInfo[path]; !ok || (withTitles && !info.titled) {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	m.readingInfo = true
+	return readFileInfo(m.fsys, missing, withTitles)
+}
+
+// setSortOrder reorders the tree.
+func (m *DualPaneModel) setSortOrder(order treeSort) tea.Cmd {
+	m.sortOrder = order
+	m.rebuildTree()
+	return tea.Batch(m.readFileInfo(), m.flash("Sort: "+order.String()))
+}
+
+// setCollapsed collapses or expands the directory at path.
+func (m *DualPaneModel) setCollapsed(path string, collapsed bool) {
+	if m.collapsed == nil {
+		m.collapsed = make(map[string]bool)
+	}
+	if collapsed {
+		m.collapsed[path] = true
+	} else {
+		delete(m.collapsed, path)
+	}
+	m.rebuildTree()
+}
+
+// revealInTree expands the directories containing path and moves the tree
+// cursor to it.
+func (m *DualPaneModel) revealInTree(path string) {
+	expanded := false
+	for dir := filepath.Dir(path); m.collapsed != nil; dir = filepath.Dir(dir) {
+		if m.collapsed[dir] {
+			delete(m.collapsed, dir)
+			expanded = true
+		}
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if expanded || len(m.treeRows) == 0 {
+		m.treeRows = m.visibleRows()
+	}
+
+	// A file hidden by the filter or the changed files view can only be
+	// shown without them
+	if m.treeLineForPath(path) < 0 && (m.filter.active() || m.changedOnly) {
+		m.filter = treeFilter{}
+		m.changedOnly = false
+		m.treeRows = m.visibleRows()
+	}
+
+	if line := m.treeLineForPath(path); line >= 0 {
+		m.treeSelectedIdx = line
+	}
+	m.adjustTreeViewport()
+}
+
+func (m *DualPaneModel) ensureRenderer() {
+	if m.renderer == nil {
+		width := 60 // Default width
+		if renderer, err := cachedRenderer(m.style, width); err == nil {
 			m.renderer = renderer
-			// Cache it
-			dualRendererMutex.Lock()
-			dualRendererCache[width] = renderer
-			dualRendererMutex.Unlock()
 		}
 	}
 }
@@ -550,16 +1288,64 @@ func (m *DualPaneModel) loadFile(index int) {
 		return
 	}
 
-	content, err := os.ReadFile(m.allFiles[index])
+	m.rememberPosition()
+
+	stamp := statFile(m.allFiles[index])
+	content, err := m.fsys.ReadFile(m.allFiles[index])
 	if err != nil {
 		m.currentContent = fmt.Sprintf("Error loading file: %v", err)
 		m.renderedLines = strings.Split(m.currentContent, "\n")
+		m.loadedPath = ""
 		return
 	}
 
 	m.currentContent = string(content)
+	m.loadedPath = m.allFiles[index]
+	m.loadedStamp = stamp
+
+	// Resume where the file was left, unless it has changed since
+	state, remembered := lookupFileState(m.loadedPath, m.currentContent)
+	if remembered {
+		m.raw = state.Raw
+	}
 	m.refreshContent()
 	m.contentViewport = 0
+	if remembered {
+		m.scrollContentTo(state.Viewport)
+	}
+}
+
+// reload replaces the displayed file with content that changed on disk,
+// keeping the scroll position. A pane scrolled to the end stays at the end.
+func (m *DualPaneModel) reload(content string) tea.Cmd {
+	availableHeight := m.height - 2
+	viewport := m.contentViewport
+	if viewport > 0 && viewport >= len(m.renderedLines)-availableHeight {
+		viewport = math.MaxInt
+	}
+
+	m.currentContent = content
+	m.refreshContent()
+	m.scrollContentTo(viewport)
+
+	// The file's sort keys and git status have changed too
+	delete(m.fileInfo, m.loadedPath)
+	return tea.Batch(m.readFileInfo(), m.loadGitStatus(), m.flash("Reloaded "+filepath.Base(m.loadedPath)))
+}
+
+// flash shows a message in the status bar for a moment.
+func (m *DualPaneModel) flash(message string) tea.Cmd {
+	m.messageID++
+	m.statusMessage = message
+	return clearStatusAfter(m.messageID)
+}
+
+// rememberPosition records the reading position of the file in the content
+// pane so it can be restored when the file is selected again.
+func (m *DualPaneModel) rememberPosition() {
+	if m.loadedPath != "" {
+		rememberFileState(m.loadedPath, m.currentContent, m.contentViewport, m.raw)
+	}
 }
 
 func (m *DualPaneModel) refreshContent() {
@@ -568,7 +1354,7 @@ func (m *DualPaneModel) refreshContent() {
 	} else {
 		m.ensureRenderer()
 		if m.renderer != nil {
-			rendered, err := m.renderer.Render(m.currentContent)
+			rendered, err := m.renderer.Render(prepareForRender(m.loadedPath, m.currentContent, !m.hideMeta))
 			if err != nil {
 				rendered = m.currentContent
 			}
@@ -578,6 +1364,36 @@ func (m *DualPaneModel) refreshContent() {
 			m.renderedLines = strings.Split(m.currentContent, "\n")
 		}
 	}
+	m.search.refresh(m.renderedLines)
+}
+
+// scrollContentTo moves the content viewport so line is at the top of the
+// pane, clamped to the scrollable range.
+func (m *DualPaneModel) scrollContentTo(line int) {
+	availableHeight := m.height - 2
+	m.contentViewport = max(0, min(line, len(m.renderedLines)-availableHeight))
+}
+
+// updateSearchInput feeds a key press to the open search prompt and moves to
+// the first match as the query is typed.
+func (m *DualPaneModel) updateSearchInput(msg tea.KeyMsg) {
+	switch m.search.handleInput(msg) {
+	case searchInputEdited:
+		m.search.refresh(m.renderedLines)
+		if line, ok := m.search.jumpFrom(m.search.origin, m.search.backward); ok {
+			m.scrollContentTo(line)
+		} else {
+			m.contentViewport = m.search.origin
+		}
+	case searchInputConfirmed:
+		m.search.refresh(m.renderedLines)
+		if line, ok := m.search.jumpFrom(m.search.origin, m.search.backward); ok {
+			m.scrollContentTo(line)
+		}
+	case searchInputCancelled:
+		m.contentViewport = m.search.origin
+		m.search.refresh(m.renderedLines)
+	}
 }
 
 func (m *DualPaneModel) adjustTreeViewport() {
@@ -598,29 +1414,7 @@ func (m *DualPaneModel) updateRendererWidth() {
 		wrappingWidth = 40 // Minimum readable width
 	}
 
-	// Check cache first
-	dualRendererMutex.RLock()
-	if cached, exists := dualRendererCache[wrappingWidth]; exists {
-		m.renderer = cached
-		dualRendererMutex.RUnlock()
-		return
-	}
-	dualRendererMutex.RUnlock()
-
-	// Create renderer with fast dark style
-	renderer, err := glamour.NewTermRenderer(
-		glamour.WithStandardStyle("dark"),
-		glamour.WithWordWrap(wrappingWidth),
-	)
-
-	// Cache successful renderer
-	if err == nil {
-		m.renderer = renderer
-		dualRendererMutex.Lock()
-		dualRendererCache[wrappingWidth] = renderer
-		dualRendererMutex.Unlock()
-	}
-	if err == nil {
+	if renderer, err := cachedRenderer(m.style, wrappingWidth); err == nil {
 		m.renderer = renderer
 		m.refreshContent()
 	}
@@ -653,23 +1447,3 @@ func maxFloat(a, b float64) float64 {
 	}
 	return b
 }
-
-func findTreeLineForFile(fileIndex int, treeLines []string, allFiles []string) int {
-	if fileIndex < 0 || fileIndex >= len(allFiles) {
-		return 0
-	}
-
-	targetFile := allFiles[fileIndex]
-	// Extract just the filename without path
-	parts := strings.Split(targetFile, "/")
-	filename := parts[len(parts)-1]
-
-	for i, line := range treeLines {
-		// Look for lines that contain the filename and are file entries (have [-])
-		if strings.Contains(line, "[-]") && strings.Contains(line, filename) {
-			return i
-		}
-	}
-
-	return 0 // Default to first line if not found
-}
@@ -1,43 +1,66 @@
 package main
 
 import (
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
-func TestFindTreeLineForFile(t *testing.T) {
-	// Sample tree lines as they would appear in the UI
-	treeLines := []string{
-		"└── [+] docs/",
-		"    ├── [-] guide.md",
-		"    └── [-] tutorial.md",
-		"└── [-] README.md",
+func TestTreeLineForPath(t *testing.T) {
+	// Two files of the same name, and names that contain each other
+	root := &FileNode{
+		Name:  "test",
+		Path:  "/test",
+		IsDir: true,
+		Children: []*FileNode{
+			{Name: "docs", Path: "/test/docs", IsDir: true, Children: []*FileNode{
+				{Name: "README.md", Path: "/test/docs/README.md"},
+				{Name: "guide.md", Path: "/test/docs/guide.md"},
+				{Name: "my-guide.md", Path: "/test/docs/my-guide.md"},
+			}},
+			{Name: "README.md", Path: "/test/README.md"},
+			{Name: "guide.md", Path: "/test/guide.md"},
+		},
 	}
 
-	// Sample file list
-	allFiles := []string{
-		"/test/docs/guide.md",
-		"/test/docs/tutorial.md",
-		"/test/README.md",
-	}
+	m := &DualPaneModel{fileTree: root, showEmptyDirs: true, height: 20}
+	m.rebuildTree()
 
 	tests := []struct {
-		fileIndex    int
+		path         string
 		expectedLine int
 		description  string
 	}{
-		{0, 1, "First file (guide.md) should map to line 1"},
-		{1, 2, "Second file (tutorial.md) should map to line 2"},
-		{2, 3, "Third file (README.md) should map to line 3"},
-		{-1, 0, "Invalid index should return 0"},
-		{10, 0, "Out of bounds index should return 0"},
+		{"/test/docs", 0, "Directory docs/ should map to line 0"},
+		{"/test/docs/guide.md", 1, "docs/guide.md should map to line 1"},
+		{"/test/docs/my-guide.md", 2, "my-guide.md should map to line 2, not guide.md's"},
+		{"/test/docs/README.md", 3, "docs/README.md should map to line 3"},
+		{"/test/guide.md", 4, "The top level guide.md should map to line 4"},
+		{"/test/README.md", 5, "The top level README.md should map to line 5"},
+		{"/test/missing.md", -1, "Unknown files should return -1"},
 	}
 
 	for _, test := range tests {
-		result := findTreeLineForFile(test.fileIndex, treeLines, allFiles)
-		if result != test.expectedLine {
+		if result := m.treeLineForPath(test.path); result != test.expectedLine {
 			t.Errorf("%s: expected line %d, got %d", test.description, test.expectedLine, result)
 		}
 	}
+
+	// Rows know their node and depth, independently of how they are drawn
+	row := m.treeRows[3]
+	if row.node != root.Children[0].Children[0] || row.depth != 1 || row.text() != "    │   └── [-] README.md" {
+		t.Errorf("Unexpected row %+v drawn as %q", row, row.text())
+	}
+
+	// Selecting the top level README.md highlights its own line
+	m.allFiles = CollectFiles(root)
+	m.revealInTree("/test/README.md")
+	if m.treeSelectedIdx != 5 {
+		t.Errorf("Expected the cursor on line 5, got %d", m.treeSelectedIdx)
+	}
 }
 
 func TestMinMax(t *testing.T) {
@@ -193,3 +216,252 @@ func TestContentViewportBounds(t *testing.T) {
 	// Verify we started with a reasonable value
 	_ = originalViewport
 }
+
+func TestDualPaneModelWithRoots(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir, paths := writeSearchFixture(t)
+	docs := filepath.Join(tempDir, "docs")
+
+	// A single directory becomes the tree root
+	m, err := NewDualPaneModelWithRoots([]string{docs}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.Update(mustScan(t, m))
+	if m.rootPath != docs || m.roots != nil {
+		t.Errorf("Expected tree rooted at %s, got %s (roots %v)", docs, m.rootPath, m.roots)
+	}
+	if len(m.allFiles) != 2 {
+		t.Errorf("Expected the 2 files in docs, got %v", m.allFiles)
+	}
+
+	// Several paths appear side by side
+	m, err = NewDualPaneModelWithRoots([]string{docs, paths[0]}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.Update(mustScan(t, m))
+	if m.rootPath != tempDir || len(m.allFiles) != 3 {
+		t.Errorf("Expected 3 files under %s, got %v under %s", tempDir, m.allFiles, m.rootPath)
+	}
+
+	if _, err := NewDualPaneModelWithRoots([]string{filepath.Join(tempDir, "missing")}, FinderOptions{}); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
+
+func TestDualPaneCollapseDirectories(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir, paths := writeSearchFixture(t)
+	docs := filepath.Join(tempDir, "docs")
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+	m.showEmptyDirs = true // docs/ has no files until the walk reaches it
+	key := func(k string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+
+	// The first scan only sees the top level
+	runScan(t, m)
+	walk := m.walk
+	if node := m.cursorNode(); node == nil || node.Path != paths[0] {
+		t.Fatalf("Expected cursor on a.md, got %+v", node)
+	}
+
+	// The cursor can land on a directory, and h collapses it
+	key("k")
+	if node := m.cursorNode(); node == nil || node.Path != docs {
+		t.Fatalf("Expected cursor on docs/, got %+v", node)
+	}
+	key("h")
+	if !m.collapsed[docs] {
+		t.Error("Expected h to collapse docs/")
+	}
+
+	// Collapsed directories stay collapsed when the background scan finds
+	// their files
+	finishWalk(t, m, walk)
+	if len(m.allFiles) != 3 {
+		t.Fatalf("Expected rescan to find 3 files, got %v", m.allFiles)
+	}
+	if len(m.treeRows) != 2 || !strings.HasSuffix(m.treeRows[0].text(), "[+] docs/") {
+		t.Errorf("Expected docs/ to stay collapsed, got %q", rowTexts(m.treeRows))
+	}
+	if node := m.cursorNode(); node == nil || node.Path != docs {
+		t.Errorf("Expected cursor to stay on docs/, got %+v", node)
+	}
+
+	// l expands it again, and h on a file moves to its directory
+	key("l")
+	if len(m.treeRows) != 4 || m.focusedPane != 0 {
+		t.Fatalf("Expected l to expand docs/, got %q", rowTexts(m.treeRows))
+	}
+	key("j")
+	if m.loadedPath != paths[1] {
+		t.Errorf("Expected b.md to load, got %s", m.loadedPath)
+	}
+	key("h")
+	if node := m.cursorNode(); node == nil || node.Path != docs {
+		t.Errorf("Expected h to move to docs/, got %+v", node)
+	}
+
+	// Tree keys do nothing while the content pane has focus
+	m.focusedPane = 1
+	for _, k := range []string{"-", "s", "E", "."} {
+		key(k)
+	}
+	if len(m.treeRows) != 4 || m.sortOrder != (treeSort{}) || !m.showEmptyDirs || m.options.IncludeHidden {
+		t.Errorf("Expected the tree to stay as it is, got %q", rowTexts(m.treeRows))
+	}
+	m.focusedPane = 0
+
+	// Collapse all and expand all
+	key("-")
+	if len(m.treeRows) != 2 {
+		t.Errorf("Expected everything collapsed, got %q", rowTexts(m.treeRows))
+	}
+	key("+")
+	if len(m.treeRows) != 4 || len(m.collapsed) != 0 {
+		t.Errorf("Expected everything expanded, got %q", rowTexts(m.treeRows))
+	}
+
+	// Selecting a hidden file reveals it
+	key("-")
+	m.selectFile(m.fileIndex(paths[2]))
+	if node := m.cursorNode(); node == nil || node.Path != paths[2] || m.collapsed[docs] {
+		t.Errorf("Expected c.md revealed under the cursor, got %+v", node)
+	}
+}
+
+func TestDualPaneWalkDiscoversDeepFiles(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		"a/b/c/d/e/f/g/deep.md": "# Deep",
+		"a/top.md":              "# Top",
+		"empty/notes.txt":       "not markdown",
+	})
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+
+	runScan(t, m)
+	if len(m.allFiles) != 0 || !m.scanning {
+		t.Fatalf("Expected no files yet and a running walk, got %v", m.allFiles)
+	}
+	if len(m.treeRows) != 0 {
+		t.Errorf("Expected directories without files hidden, got %q", rowTexts(m.treeRows))
+	}
+
+	finishWalk(t, m, m.walk)
+	if len(m.allFiles) != 2 || m.scanning {
+		t.Fatalf("Expected the walk to find both files, got %v", m.allFiles)
+	}
+	// Which file is found first depends on how the walker's batches arrive
+	if m.fileIndex(m.loadedPath) < 0 {
+		t.Errorf("Expected the first file found to be shown, got %q", m.loadedPath)
+	}
+	if len(m.treeRows) != 9 || slices.ContainsFunc(m.treeRows, func(row treeRow) bool { return row.node.Name == "empty" }) {
+		t.Errorf("Expected only directories with markdown files, got %q", rowTexts(m.treeRows))
+	}
+
+	// E shows the empty ones
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("E")})
+	if len(m.treeRows) != 10 {
+		t.Errorf("Expected empty/ to be shown, got %q", rowTexts(m.treeRows))
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("E")})
+
+	// Results of a cancelled walk are dropped
+	runScan(t, m)
+	stale := m.walk
+	runScan(t, m)
+	stale.stop()
+	m.Update(walkBatchMsg{id: stale.id, entries: []walkEntry{{path: filepath.Join(tempDir, "stale.md")}}})
+	if m.fileIndex(filepath.Join(tempDir, "stale.md")) >= 0 {
+		t.Error("Expected entries of a cancelled walk to be ignored")
+	}
+	finishWalk(t, m, m.walk)
+	if len(m.allFiles) != 2 {
+		t.Errorf("Expected a rescan to find the same files, got %v", m.allFiles)
+	}
+
+	// Of overlapping scans, an older one finishing last is dropped
+	older := m.scan()
+	newer := m.scan()
+	m.Update(newer())
+	walk := m.walk
+	if _, cmd := m.Update(older()); cmd != nil || m.walk != walk {
+		t.Error("Expected the result of a superseded scan to be ignored")
+	}
+	finishWalk(t, m, m.walk)
+}
+
+// runScan starts a scan of a dual pane model's tree. Its walk, and any walk
+// started after it, is stopped when the test ends.
+func runScan(t *testing.T, m *DualPaneModel) {
+	t.Helper()
+	t.Cleanup(func() { m.walk.stop() })
+	m.Update(m.scan()())
+}
+
+func TestDualPaneToggleHidden(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		".github/CONTRIBUTING.md": "# Contributing",
+		"README.md":               "# Readme",
+	})
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+	if len(m.allFiles) != 1 {
+		t.Fatalf("Expected hidden files left out, got %v", m.allFiles)
+	}
+
+	// The tree is scanned again with them
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(".")})
+	if cmd == nil || !m.options.IncludeHidden || m.statusMessage != "Showing dotfiles" {
+		t.Fatalf("Expected . to rescan with hidden files, got %+v", m.options)
+	}
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+	if len(m.allFiles) != 2 {
+		t.Errorf("Expected .github/CONTRIBUTING.md to be found, got %v", m.allFiles)
+	}
+	if m.loadedPath != filepath.Join(tempDir, "README.md") {
+		t.Errorf("Expected the shown file to stay, got %s", m.loadedPath)
+	}
+}
+
+// finishWalk feeds the results of a dual pane model's walk to it until the
+// walk is done.
+func finishWalk(t *testing.T, m *DualPaneModel, walk *treeWalker) {
+	t.Helper()
+	for m.walk == walk && walk != nil {
+		m.Update(walk.next()())
+	}
+}
+
+// mustScan runs the full scan of a dual pane model's tree. The walk started
+// by its result is stopped when the test ends.
+func mustScan(t *testing.T, m *DualPaneModel) loadCompleteMsg {
+	t.Helper()
+	t.Cleanup(func() { m.walk.stop() })
+	tree, err := scanTree(m.fsys, m.rootPath, m.roots, m.options, -1)
+	if err != nil {
+		t.Fatalf("scanTree failed: %v", err)
+	}
+	return loadCompleteMsg{id: m.scanID, tree: tree}
+}
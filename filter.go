@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// treeFilter narrows the tree to the files whose path matches the typed
+// text, keeping the directories that lead to them.
+type treeFilter struct {
+	input   string
+	typing  bool
+	match   func(path string) bool
+	restore string // File shown before filtering, selected again when cleared
+}
+
+func (f *treeFilter) active() bool {
+	return f.typing || f.input != ""
+}
+
+// filterTree returns a copy of the tree with only the files for which keep
+// returns true, and the directories containing them. keep is given paths
+// relative to base, with forward slashes.
+func filterTree(node *FileNode, base string, keep func(path string) bool) *FileNode {
+	if node == nil {
+		return nil
+	}
+
+	filtered := *node
+	filtered.Children = nil
+	for _, child := range node.Children {
+		if child.IsDir {
+			if dir := filterTree(child, base, keep); len(dir.Children) > 0 {
+				filtered.Children = append(filtered.Children, dir)
+			}
+			continue
+		}
+		if rel, err := filepath.Rel(base, child.Path); err == nil && keep(filepath.ToSlash(rel)) {
+			filtered.Children = append(filtered.Children, child)
+		}
+	}
+	return &filtered
+}
+
+// beginFilter opens the filter prompt of the tree pane.
+func (m *DualPaneModel) beginFilter() {
+	if !m.filter.active() {
+		m.filter.restore = m.loadedPath
+	}
+	m.filter.typing = true
+}
+
+// updateFilterInput handles a key press while the filter prompt is open.
+// The tree is narrowed as the text changes.
+func (m *DualPaneModel) updateFilterInput(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filter.typing = false
+		if m.filter.input == "" {
+			m.clearFilter()
+		}
+		return
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.clearFilter()
+		return
+	}
+
+	if input, ok := editPrompt(m.filter.input, msg); ok && input != m.filter.input {
+		m.filter.input = input
+		m.filter.match = nil
+		if input != "" {
+			m.filter.match = pathMatcher(input)
+		}
+		m.applyFilter()
+	}
+}
+
+// applyFilter rebuilds the tree for the current filter and keeps the
+// selection on a visible file: the one shown if it still matches, otherwise
+// the first match.
+func (m *DualPaneModel) applyFilter() {
+	m.rebuildTree()
+	m.selectVisibleFile()
+}
+
+// clearFilter shows the whole tree again, with the file that was selected
+// before filtering.
+func (m *DualPaneModel) clearFilter() {
+	restore := m.filter.restore
+	m.filter = treeFilter{}
+	m.rebuildTree()
+
+	if index := m.fileIndex(restore); index >= 0 {
+		m.selectFile(index)
+	} else if line := m.treeLineForPath(m.loadedPath); line >= 0 {
+		m.treeSelectedIdx = line
+		m.adjustTreeViewport()
+	}
+}
+
+// visibleFileCount returns the number of files in the tree pane.
+func (m *DualPaneModel) visibleFileCount() int {
+	count := 0
+	for _, row := range m.treeRows {
+		if !row.node.IsDir {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDualPaneTreeFilter(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		"README.md":               "# Readme",
+		"guide/setup.md":          "# Setup",
+		"services/api/users.md":   "# Users",
+		"services/api/orders.md":  "# Orders",
+		"services/web/index.md":   "# Web",
+		"legacy/old/api/notes.md": "# Notes",
+	})
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+	m.width, m.height = 400, 30 // Wide enough for the whole status bar
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+
+	setup := filepath.Join(tempDir, "guide", "setup.md")
+	m.selectFile(m.fileIndex(setup))
+
+	key := func(keys ...tea.KeyMsg) {
+		for _, k := range keys {
+			m.Update(k)
+		}
+	}
+	runes := func(s string) tea.KeyMsg {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+	visible := func() []string {
+		var files []string
+		for _, row := range m.treeRows {
+			if !row.node.IsDir {
+				rel, _ := filepath.Rel(tempDir, row.node.Path)
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		return files
+	}
+
+	// The tree narrows as the pattern is typed
+	key(runes("/"), runes("*/api/"))
+	if files := visible(); len(files) != 0 {
+		t.Errorf("Expected no matches for a partial pattern, got %v", files)
+	}
+	key(runes("*.md"))
+	files := visible()
+	if len(files) != 3 || strings.Join(files, " ") != "legacy/old/api/notes.md services/api/orders.md services/api/users.md" {
+		t.Errorf("Expected the three api files, got %v", files)
+	}
+
+	// Directories leading to matches stay, others go
+	tree := strings.Join(rowTexts(m.treeRows), "\n")
+	if !strings.Contains(tree, "services/") || !strings.Contains(tree, "old/") || strings.Contains(tree, "web/") {
+		t.Errorf("Expected ancestors of matches only, got\n%s", tree)
+	}
+
+	// The selection moves to a visible file and stays consistent
+	node := m.cursorNode()
+	if node == nil || node.IsDir || node.Path != m.loadedPath || m.currentFile() != m.loadedPath {
+		t.Errorf("Expected the cursor on the shown file, got %+v with %s shown", node, m.loadedPath)
+	}
+
+	// Enter keeps the filter for browsing
+	key(tea.KeyMsg{Type: tea.KeyEnter}, runes("j"))
+	if m.filter.typing || m.filter.input != "*/api/*.md" {
+		t.Errorf("Expected the filter to stay after enter, got %+v", m.filter)
+	}
+	if !strings.Contains(m.View(), "Filter: */api/*.md (3 files)") {
+		t.Error("Expected the filter in the status bar")
+	}
+
+	// Esc restores the whole tree and the previous selection
+	key(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.filter.active() || len(visible()) != 6 {
+		t.Errorf("Expected the filter cleared, got %v", visible())
+	}
+	if m.loadedPath != setup || m.cursorNode().Path != setup {
+		t.Errorf("Expected %s selected again, got %s", setup, m.loadedPath)
+	}
+
+	// A substring filter is cleared by opening a hidden file
+	key(runes("/"), runes("web"), tea.KeyMsg{Type: tea.KeyEnter})
+	if files := visible(); len(files) != 1 {
+		t.Errorf("Expected only the web file, got %v", files)
+	}
+	m.selectFile(m.fileIndex(setup))
+	if m.filter.active() || m.cursorNode().Path != setup {
+		t.Error("Expected selecting a filtered out file to clear the filter")
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-
-	"github.com/denormal/go-gitignore"
 )
 
 type FileNode struct {
@@ -17,144 +15,199 @@ type FileNode struct {
 	Children []*FileNode
 }
 
+// FinderOptions selects the files the finder functions include besides
+// the markdown files that aren't hidden or ignored.
+type FinderOptions struct {
+	IncludeIgnored bool     // Files excluded by gitignore rules
+	IncludeHidden  bool     // Dotfiles and dot-directories, except .git
+	Include        []string // If set, only files matching one of these gitignore style patterns
+	Exclude        []string // Files and directories to leave out, as gitignore style patterns
+}
+
 func FindMarkdownFiles(rootPath string, includeIgnored bool) (*FileNode, error) {
 	return FindMarkdownFilesWithDepth(rootPath, includeIgnored, -1)
 }
 
 func FindMarkdownFilesQuick(rootPath string, includeIgnored bool) (*FileNode, error) {
+	return FindMarkdownFilesQuickWithOptions(rootPath, FinderOptions{IncludeIgnored: includeIgnored})
+}
+
+func FindMarkdownFilesQuickWithOptions(rootPath string, opts FinderOptions) (*FileNode, error) {
 	// Ultra-fast scan of just the current directory (no subdirs)
-	var ignore gitignore.GitIgnore
+	return treeFS{}.findMarkdownFiles(rootPath, opts, 0)
+}
 
-	if !includeIgnored {
-		gitignorePath := filepath.Join(rootPath, ".gitignore")
-		if _, err := os.Stat(gitignorePath); err == nil {
-			ignore, _ = gitignore.NewFromFile(gitignorePath)
-		}
-	}
+func FindMarkdownFilesWithDepth(rootPath string, includeIgnored bool, maxDepth int) (*FileNode, error) {
+	return FindMarkdownFilesWithOptions(rootPath, FinderOptions{IncludeIgnored: includeIgnored}, maxDepth)
+}
+
+func FindMarkdownFilesWithOptions(rootPath string, opts FinderOptions, maxDepth int) (*FileNode, error) {
+	return treeFS{}.findMarkdownFiles(rootPath, opts, maxDepth)
+}
 
+// walkMarkdownFiles builds the tree of the markdown files in fsys, which
+// holds the contents of the directory at rootPath. Nodes get the paths of
+// the files below rootPath.
+func walkMarkdownFiles(fsys fs.FS, rootPath string, filter *entryFilter, maxDepth int) (*FileNode, error) {
 	root := &FileNode{
 		Name:  filepath.Base(rootPath),
 		Path:  rootPath,
 		IsDir: true,
 	}
 
-	entries, err := os.ReadDir(rootPath)
-	if err != nil {
-		return root, nil // Return empty root on error
-	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
 
-	for _, entry := range entries {
-		name := entry.Name()
-		fullPath := filepath.Join(rootPath, name)
+		// Skip the root directory itself
+		if name == "." {
+			return nil
+		}
 
-		// Skip hidden files/dirs starting with .
-		if strings.HasPrefix(name, ".") {
-			continue
+		// Skip if we've exceeded max depth
+		depth := strings.Count(name, "/")
+		if maxDepth >= 0 && depth > maxDepth {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
 		}
 
-		// Check gitignore
-		if !includeIgnored && ignore != nil {
-			if ignore.Ignore(name) {
-				continue
+		// Skip hidden, ignored and non-markdown entries
+		path := filepath.Join(rootPath, filepath.FromSlash(name))
+		if !filter.include(path, d) {
+			if d.IsDir() {
+				return fs.SkipDir
 			}
+			return nil
 		}
 
-		// Only include markdown files and directories
-		if entry.IsDir() {
-			// Add directory to tree
-			addToTree(root, rootPath, fullPath, true)
-		} else if strings.HasSuffix(strings.ToLower(name), ".md") {
-			// Add markdown file to tree
-			addToTree(root, rootPath, fullPath, false)
+		// Add to tree
+		addToTree(root, rootPath, path, d.IsDir())
+
+		// Don't read directories whose entries are too deep anyway
+		if d.IsDir() && depth == maxDepth {
+			return fs.SkipDir
 		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	// Sort children
+	// Sort children at each level
 	sortTree(root)
+
 	return root, nil
 }
 
-func FindMarkdownFilesWithDepth(rootPath string, includeIgnored bool, maxDepth int) (*FileNode, error) {
-	var ignore gitignore.GitIgnore
-
-	if !includeIgnored {
-		gitignorePath := filepath.Join(rootPath, ".gitignore")
-		if _, err := os.Stat(gitignorePath); err == nil {
-			ignore, _ = gitignore.NewFromFile(gitignorePath)
-		}
-	}
-
+// FindMarkdownFilesInPaths builds a tree of several files and directories,
+// such as the paths given on the command line. Each path becomes a top level
+// node named as given, in the order given; directories are scanned to
+// maxDepth like FindMarkdownFilesWithOptions.
+func FindMarkdownFilesInPaths(paths []string, opts FinderOptions, maxDepth int) (*FileNode, error) {
+	abs := allAbsPaths(paths)
 	root := &FileNode{
-		Name:  filepath.Base(rootPath),
-		Path:  rootPath,
+		Name:  "",
+		Path:  commonDir(abs),
 		IsDir: true,
 	}
 
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+	for i, path := range paths {
+		info, err := os.Stat(abs[i])
 		if err != nil {
-			return nil
+			return nil, err
 		}
 
-		// Calculate depth
-		if maxDepth >= 0 {
-			relPath, _ := filepath.Rel(rootPath, path)
-			depth := len(strings.Split(relPath, string(filepath.Separator))) - 1
-			if relPath == "." {
-				depth = 0
-			}
-
-			// Skip if we've exceeded max depth
-			if depth > maxDepth {
-				if d.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
+		if !info.IsDir() {
+			root.Children = append(root.Children, &FileNode{Name: filepath.Clean(path), Path: abs[i]})
+			continue
 		}
 
-		// Skip hidden directories (starting with .)
-		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != rootPath {
-			return filepath.SkipDir
+		node, err := FindMarkdownFilesWithOptions(abs[i], opts, maxDepth)
+		if err != nil {
+			return nil, err
 		}
+		node.Name = filepath.Clean(path)
+		root.Children = append(root.Children, node)
+	}
 
-		// Check gitignore
-		if !includeIgnored && ignore != nil {
-			relPath, err := filepath.Rel(rootPath, path)
-			if err == nil && relPath != "." {
-				// Safely check if file should be ignored
-				if ignore.Ignore(relPath) {
-					if d.IsDir() {
-						return filepath.SkipDir
-					}
-					return nil
-				}
-			}
-		}
+	return root, nil
+}
 
-		// Only include markdown files and directories
-		if !d.IsDir() && !strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
-			return nil
+// commonDir returns the deepest directory containing all of paths.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	dir := func(path string) string {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
 		}
+		return filepath.Dir(path)
+	}
 
-		// Skip the root directory itself
-		if path == rootPath {
-			return nil
+	common := dir(paths[0])
+	for _, path := range paths[1:] {
+		path = dir(path)
+		for common != filepath.Dir(common) {
+			rel, err := filepath.Rel(common, path)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+			common = filepath.Dir(common)
 		}
+	}
+	return common
+}
 
-		// Add to tree
-		addToTree(root, rootPath, path, d.IsDir())
+// entryFilter decides which directory entries belong in the tree.
+type entryFilter struct {
+	ignore   *ignoreMatcher // nil when ignored files are included
+	mdignore *patternSet
+	includes *patternSet // Patterns relative to the scanned root
+	excludes *patternSet
+	hidden   bool
+}
 
-		return nil
-	})
+// newEntryFilter returns the filter for entries under root.
+func newEntryFilter(root string, opts FinderOptions) *entryFilter {
+	filter := &entryFilter{
+		mdignore: newMdignore(root),
+		includes: newPatternSet(root, opts.Include),
+		excludes: newPatternSet(root, opts.Exclude),
+		hidden:   opts.IncludeHidden,
+	}
+	if !opts.IncludeIgnored {
+		filter.ignore = newIgnoreMatcher(root)
+	}
+	return filter
+}
 
-	if err != nil {
-		return nil, err
+// include reports whether a directory entry belongs in the tree: it must be
+// a markdown file or a directory that is neither hidden nor ignored.
+func (f *entryFilter) include(fullPath string, entry fs.DirEntry) bool {
+	// Skip hidden files/dirs starting with ., and git's own directory
+	// even when hidden ones are shown
+	if entry.Name() == ".git" || (!f.hidden && strings.HasPrefix(entry.Name(), ".")) {
+		return false
 	}
 
-	// Sort children at each level
-	sortTree(root)
+	// Check gitignore, .mdignore and --exclude
+	isDir := entry.IsDir()
+	if f.ignore.ignored(fullPath, isDir) || f.mdignore.matches(fullPath, isDir) || f.excludes.matches(fullPath, isDir) {
+		return false
+	}
 
-	return root, nil
+	// Only include markdown files and directories; --include only narrows
+	// down files, since any directory may contain matching ones
+	if isDir {
+		return true
+	}
+	return isMarkdownFile(entry.Name()) && (f.includes == nil || f.includes.matches(fullPath, false))
 }
 
 func addToTree(root *FileNode, basePath, fullPath string, isDir bool) {
@@ -189,12 +242,8 @@ func sortTree(node *FileNode) {
 		return
 	}
 
-	// Sort: directories first, then alphabetically
 	sort.Slice(node.Children, func(i, j int) bool {
-		if node.Children[i].IsDir != node.Children[j].IsDir {
-			return node.Children[i].IsDir
-		}
-		return strings.ToLower(node.Children[i].Name) < strings.ToLower(node.Children[j].Name)
+		return nodeLess(node.Children[i], node.Children[j])
 	})
 
 	// Recursively sort children
@@ -203,28 +252,103 @@ func sortTree(node *FileNode) {
 	}
 }
 
+// nodeLess orders directories first, then alphabetically.
+func nodeLess(a, b *FileNode) bool {
+	if a.IsDir != b.IsDir {
+		return a.IsDir
+	}
+	return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+}
+
+// insertNode adds child to parent's children in sorted position. It returns
+// false if parent already has a child of the same name.
+func insertNode(parent, child *FileNode) bool {
+	i := sort.Search(len(parent.Children), func(i int) bool {
+		return !nodeLess(parent.Children[i], child)
+	})
+	for _, existing := range parent.Children {
+		if existing.Name == child.Name {
+			return false
+		}
+	}
+
+	parent.Children = append(parent.Children, nil)
+	copy(parent.Children[i+1:], parent.Children[i:])
+	parent.Children[i] = child
+	return true
+}
+
+// FlattenTree renders the tree as display lines with every directory
+// expanded. The root node itself is not shown.
 func FlattenTree(node *FileNode, prefix string, isLast bool) []string {
-	var lines []string
+	var rows []treeRow
+	appendTreeRows(&rows, node, prefix, isLast, -1, nil)
+	return rowTexts(rows)
+}
+
+// treeRow is one line of the tree pane: the node it shows and how to draw
+// it.
+type treeRow struct {
+	node      *FileNode
+	depth     int    // 0 for the top level
+	guide     string // Tree lines drawn before the entry, e.g. "│   ├── "
+	collapsed bool
+	marker    string // Shown after the name, such as the git status
+}
+
+// text renders the row. Directories are marked [+] when collapsed and [-]
+// when expanded; files are marked [-].
+func (r treeRow) text() string {
+	text := r.guide
+	switch {
+	case r.node.IsDir && r.collapsed:
+		text += "[+] " + r.node.Name + "/"
+	case r.node.IsDir:
+		text += "[-] " + r.node.Name + "/"
+	default:
+		text += "[-] " + r.node.Name
+	}
+	if r.marker != "" {
+		text += " " + r.marker
+	}
+	return text
+}
+
+// rowTexts renders rows as display lines.
+func rowTexts(rows []treeRow) []string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = row.text()
+	}
+	return lines
+}
 
+// treeRows returns the visible rows of the tree below node. The children of
+// directories whose path is in collapsed are hidden.
+func treeRows(node *FileNode, collapsed map[string]bool) []treeRow {
+	var rows []treeRow
+	appendTreeRows(&rows, node, "", false, -1, collapsed)
+	return rows
+}
+
+func appendTreeRows(rows *[]treeRow, node *FileNode, prefix string, isLast bool, depth int, collapsed map[string]bool) {
 	if node == nil {
-		return lines
+		return
 	}
 
-	// Create the display line
+	// Create the display row
 	if prefix != "" {
-		var line string
+		row := treeRow{node: node, depth: depth, collapsed: node.IsDir && collapsed[node.Path]}
 		if isLast {
-			line = prefix[0:len(prefix)-4] + "└── "
+			row.guide = prefix[0:len(prefix)-4] + glyphs.treeLast
 		} else {
-			line = prefix[0:len(prefix)-4] + "├── "
+			row.guide = prefix[0:len(prefix)-4] + glyphs.treeBranch
 		}
+		*rows = append(*rows, row)
 
-		if node.IsDir {
-			line += "[+] " + node.Name + "/"
-		} else {
-			line += "[-] " + node.Name
+		if row.collapsed {
+			return
 		}
-		lines = append(lines, line)
 	}
 
 	// Update prefix for children
@@ -234,17 +358,51 @@ func FlattenTree(node *FileNode, prefix string, isLast bool) []string {
 	} else if isLast {
 		newPrefix = prefix[0:len(prefix)-4] + "    "
 	} else {
-		newPrefix = prefix[0:len(prefix)-4] + "│   "
+		newPrefix = prefix[0:len(prefix)-4] + glyphs.treePipe
 	}
 
 	// Process children
 	for i, child := range node.Children {
 		childIsLast := i == len(node.Children)-1
-		childLines := FlattenTree(child, newPrefix+"    ", childIsLast)
-		lines = append(lines, childLines...)
+		appendTreeRows(rows, child, newPrefix+"    ", childIsLast, depth+1, collapsed)
 	}
+}
 
-	return lines
+// PruneEmptyDirs returns a copy of the tree without the directories that
+// have no markdown files below them. File nodes are shared with the original.
+func PruneEmptyDirs(node *FileNode) *FileNode {
+	if node == nil {
+		return nil
+	}
+
+	pruned := *node
+	pruned.Children = nil
+	for _, child := range node.Children {
+		if !child.IsDir {
+			pruned.Children = append(pruned.Children, child)
+		} else if dir := PruneEmptyDirs(child); len(dir.Children) > 0 {
+			pruned.Children = append(pruned.Children, dir)
+		}
+	}
+	return &pruned
+}
+
+// CollectDirs returns the paths of all directories below node.
+func CollectDirs(node *FileNode) []string {
+	var dirs []string
+
+	if node == nil {
+		return dirs
+	}
+
+	for _, child := range node.Children {
+		if child.IsDir {
+			dirs = append(dirs, child.Path)
+			dirs = append(dirs, CollectDirs(child)...)
+		}
+	}
+
+	return dirs
 }
 
 func CollectFiles(node *FileNode) []string {
@@ -113,7 +113,7 @@ func TestFlattenTree(t *testing.T) {
 	hasDir := false
 	hasFile := false
 	for _, line := range lines {
-		if strings.Contains(line, "[+]") && strings.HasSuffix(line, "/") {
+		if strings.Contains(line, "[-]") && strings.HasSuffix(line, "/") {
 			hasDir = true
 		}
 		if strings.Contains(line, "[-]") && strings.HasSuffix(line, ".md") {
@@ -126,6 +126,92 @@ func TestFlattenTree(t *testing.T) {
 	}
 }
 
+func TestFlattenTreeCollapsed(t *testing.T) {
+	root := &FileNode{
+		Name:  "root",
+		Path:  "/test",
+		IsDir: true,
+		Children: []*FileNode{
+			{
+				Name:  "dir1",
+				Path:  "/test/dir1",
+				IsDir: true,
+				Children: []*FileNode{
+					{Name: "file1.md", IsDir: false, Path: "/test/dir1/file1.md"},
+				},
+			},
+			{Name: "file2.md", IsDir: false, Path: "/test/file2.md"},
+		},
+	}
+
+	rows := treeRows(root, map[string]bool{"/test/dir1": true})
+	if len(rows) != 2 {
+		t.Fatalf("Expected the collapsed directory's children hidden, got %q", rowTexts(rows))
+	}
+	if !rows[0].collapsed || !strings.HasSuffix(rows[0].text(), "[+] dir1/") {
+		t.Errorf("Expected collapsed marker on dir1, got %q", rows[0].text())
+	}
+	if rows[0].node.Path != "/test/dir1" || rows[1].node.Path != "/test/file2.md" {
+		t.Errorf("Expected nodes to match the lines, got %s and %s", rows[0].node.Path, rows[1].node.Path)
+	}
+
+	if dirs := CollectDirs(root); len(dirs) != 1 || dirs[0] != "/test/dir1" {
+		t.Errorf("Expected CollectDirs to return dir1, got %v", dirs)
+	}
+}
+
+func TestInsertNode(t *testing.T) {
+	root := &FileNode{Name: "root", IsDir: true}
+	for _, child := range []*FileNode{
+		{Name: "b.md"},
+		{Name: "Z", IsDir: true},
+		{Name: "A.md"},
+		{Name: "a", IsDir: true},
+	} {
+		if !insertNode(root, child) {
+			t.Errorf("Expected %s to be inserted", child.Name)
+		}
+	}
+	if insertNode(root, &FileNode{Name: "b.md"}) {
+		t.Error("Expected a duplicate name to be rejected")
+	}
+
+	expected := []string{"a", "Z", "A.md", "b.md"}
+	for i, name := range expected {
+		if root.Children[i].Name != name {
+			t.Errorf("Child %d: expected %s, got %s", i, name, root.Children[i].Name)
+		}
+	}
+}
+
+func TestPruneEmptyDirs(t *testing.T) {
+	root := &FileNode{
+		Name:  "root",
+		IsDir: true,
+		Children: []*FileNode{
+			{Name: "docs", IsDir: true, Children: []*FileNode{
+				{Name: "empty", IsDir: true},
+				{Name: "guide.md"},
+			}},
+			{Name: "node_modules", IsDir: true, Children: []*FileNode{
+				{Name: "pkg", IsDir: true},
+			}},
+			{Name: "README.md"},
+		},
+	}
+
+	pruned := PruneEmptyDirs(root)
+	if len(pruned.Children) != 2 || pruned.Children[0].Name != "docs" || pruned.Children[1].Name != "README.md" {
+		t.Fatalf("Expected docs and README.md to remain, got %+v", pruned.Children)
+	}
+	if docs := pruned.Children[0]; len(docs.Children) != 1 || docs.Children[0].Name != "guide.md" {
+		t.Errorf("Expected the empty subdirectory to be pruned, got %+v", docs.Children)
+	}
+	if len(root.Children) != 3 || len(root.Children[0].Children) != 2 {
+		t.Error("Expected the original tree to be unchanged")
+	}
+}
+
 func TestCollectFiles(t *testing.T) {
 	root := &FileNode{
 		Name:  "root",
@@ -285,3 +371,91 @@ func TestFindMarkdownFilesIntegration(t *testing.T) {
 		t.Error("Expected to find guide.md")
 	}
 }
+
+func TestFindMarkdownFilesHidden(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		".git/info/exclude":             "",
+		".git/notes.md":                 "# Not a document",
+		".gitignore":                    ".cache/\n",
+		".cache/generated.md":           "# Generated",
+		".github/CONTRIBUTING.md":       "# Contributing",
+		".github/ISSUE_TEMPLATE/bug.md": "# Bug",
+		".changeset/brave-cats.md":      "# Changeset",
+		".notes.md":                     "# Notes",
+		"README.md":                     "# Readme",
+	})
+
+	count := func(opts FinderOptions) int {
+		tree, err := FindMarkdownFilesWithOptions(tempDir, opts, -1)
+		if err != nil {
+			t.Fatalf("FindMarkdownFilesWithOptions failed: %v", err)
+		}
+		return len(CollectFiles(tree))
+	}
+
+	if got := count(FinderOptions{}); got != 1 {
+		t.Errorf("Expected only README.md without hidden files, got %d files", got)
+	}
+	if got := count(FinderOptions{IncludeHidden: true}); got != 5 {
+		t.Errorf("Expected hidden files except ignored ones, got %d files", got)
+	}
+	if got := count(FinderOptions{IncludeHidden: true, IncludeIgnored: true}); got != 6 {
+		t.Errorf("Expected everything but .git, got %d files", got)
+	}
+
+	quick, _ := FindMarkdownFilesQuickWithOptions(tempDir, FinderOptions{IncludeHidden: true})
+	var names []string
+	for _, child := range quick.Children {
+		names = append(names, child.Name)
+	}
+	if strings.Join(names, " ") != ".changeset .github .notes.md README.md" {
+		t.Errorf("Expected hidden entries at the top level without .git and .cache, got %v", names)
+	}
+}
+
+func TestFindMarkdownFilesInPaths(t *testing.T) {
+	tempDir, paths := writeSearchFixture(t)
+	docs := filepath.Join(tempDir, "docs")
+
+	tree, err := FindMarkdownFilesInPaths([]string{docs, paths[0]}, FinderOptions{}, -1)
+	if err != nil {
+		t.Fatalf("FindMarkdownFilesInPaths failed: %v", err)
+	}
+
+	if tree.Path != tempDir {
+		t.Errorf("Expected root at the common directory %s, got %s", tempDir, tree.Path)
+	}
+	if len(tree.Children) != 2 || tree.Children[0].Name != docs || tree.Children[1].Name != paths[0] {
+		t.Fatalf("Expected docs then a.md at the top level in argument order, got %+v", tree.Children)
+	}
+
+	files := CollectFiles(tree)
+	expected := []string{paths[1], paths[2], paths[0]}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, files)
+	}
+
+	if _, err := FindMarkdownFilesInPaths([]string{filepath.Join(tempDir, "missing")}, FinderOptions{}, -1); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	tempDir, paths := writeSearchFixture(t)
+
+	tests := []struct {
+		paths    []string
+		expected string
+	}{
+		{[]string{paths[1], paths[2]}, filepath.Join(tempDir, "docs")},
+		{[]string{paths[0], paths[2]}, tempDir},
+		{[]string{filepath.Join(tempDir, "docs")}, filepath.Join(tempDir, "docs")},
+	}
+
+	for _, test := range tests {
+		if got := commonDir(test.paths); got != test.expected {
+			t.Errorf("commonDir(%v) = %s, expected %s", test.paths, got, test.expected)
+		}
+	}
+}
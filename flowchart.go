@@ -0,0 +1,580 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// flowNode is a node of a flowchart, or a dummy point that an edge crossing
+// several ranks passes through.
+type flowNode struct {
+	id      string
+	label   string
+	rounded bool
+	dummy   bool
+	rank    int
+
+	// Nodes joined to this one in the ranks above and below, set by layout
+	above, below []*flowNode
+
+	// Layout, across the ranks (x top-down, y left-right) and along them
+	across int
+	size   int // Extent across the ranks
+	length int // Extent along the ranks, 0 for dummies
+}
+
+// center is the position across the ranks where edges meet the node.
+func (n *flowNode) center() int {
+	return n.across + n.size/2
+}
+
+type flowEdge struct {
+	from, to *flowNode
+	label    string
+	arrow    bool
+	dotted   bool
+	hidden   bool // Only affects the layout
+	reversed bool // Drawn against the ranks because it closes a cycle
+}
+
+// ends returns the nodes of an edge in rank order.
+func (e *flowEdge) ends() (upper, lower *flowNode) {
+	if e.reversed {
+		return e.to, e.from
+	}
+	return e.from, e.to
+}
+
+// flowHop is the part of an edge between two adjacent ranks.
+type flowHop struct {
+	edge        *flowEdge
+	from, to    *flowNode
+	first, last bool
+
+	fromAt, toAt int // Where the hop meets its nodes across the ranks
+}
+
+// Flowcharts with more nodes or edges than these are shown as their source.
+// Their drawings would be too big to read, and slow to lay out.
+const (
+	maxFlowNodes = 50
+	maxFlowEdges = 100
+)
+
+// flowchart is a parsed flowchart. BT and RL charts are drawn top-down and
+// left-right.
+type flowchart struct {
+	nodes      []*flowNode
+	byID       map[string]*flowNode
+	edges      []*flowEdge
+	horizontal bool
+}
+
+var (
+	flowIDPattern   = regexp.MustCompile(`^[\p{L}\p{N}_]+(?:-[\p{L}\p{N}_]+)*`)
+	flowLinkPattern = regexp.MustCompile(`^\s*(<)?(-\.+-|-{2,}|={2,}|~{3,})([>ox])?\s*(?:\|([^|]*)\|)?\s*`)
+	// Links with their text in the middle, as in A -- text --> B
+	flowTextLinkPattern = regexp.MustCompile(`(--|==|-\.)\s+([^|>]+?)\s+(-->|---|==>|===|\.->|\.-)`)
+	flowClassPattern    = regexp.MustCompile(`^:::[\w-]+`)
+)
+
+// flowShapes are the brackets around node text. Earlier entries take
+// precedence over the shorter brackets they start with.
+var flowShapes = []struct {
+	open, close string
+	rounded     bool
+}{
+	{"(((", ")))", true}, {"((", "))", true}, {"([", "])", true}, {"[(", ")]", true},
+	{"[[", "]]", false}, {"{{", "}}", true}, {"[/", "/]", false}, {"[/", `\]`, false},
+	{`[\`, `\]`, false}, {`[\`, "/]", false}, {"(", ")", true}, {"[", "]", false},
+	{"{", "}", true}, {">", "]", false},
+}
+
+// flowSkipped are statements that don't affect the drawing. Subgraphs are
+// drawn as if their nodes were at the top level.
+var flowSkipped = []string{"subgraph", "end", "direction", "classDef", "class", "style", "linkStyle", "click", "accTitle", "accDescr"}
+
+// renderFlowchart draws a flowchart with its nodes in ranks, top-down or
+// left-right depending on direction. ok is false if a statement can't be
+// parsed.
+func renderFlowchart(direction string, lines []string) (string, bool) {
+	f := &flowchart{
+		byID:       make(map[string]*flowNode),
+		horizontal: direction == "LR" || direction == "RL",
+	}
+	for _, line := range lines {
+		for _, statement := range strings.Split(line, ";") {
+			statement = strings.TrimSpace(statement)
+			if statement == "" {
+				continue
+			}
+			keyword := strings.TrimRight(strings.Fields(statement)[0], ":")
+			if slices.Contains(flowSkipped, keyword) {
+				continue
+			}
+			if !f.parse(statement) {
+				return "", false
+			}
+		}
+	}
+	if len(f.nodes) == 0 || len(f.nodes) > maxFlowNodes || len(f.edges) > maxFlowEdges {
+		return "", false
+	}
+	return f.draw(), true
+}
+
+// parse adds the nodes and edges of a statement such as A[Start] --> B & C.
+func (f *flowchart) parse(statement string) bool {
+	statement = flowTextLinkPattern.ReplaceAllStringFunc(statement, func(link string) string {
+		match := flowTextLinkPattern.FindStringSubmatch(link)
+		arrow := match[3]
+		if match[1] == "-." {
+			arrow = "-" + arrow
+		}
+		return arrow + "|" + match[2] + "|"
+	})
+
+	from, rest, ok := f.parseNodes(statement)
+	if !ok {
+		return false
+	}
+	for strings.TrimSpace(rest) != "" {
+		link := flowLinkPattern.FindStringSubmatch(rest)
+		if link == nil {
+			return false
+		}
+		var to []*flowNode
+		if to, rest, ok = f.parseNodes(rest[len(link[0]):]); !ok {
+			return false
+		}
+
+		for _, a := range from {
+			for _, b := range to {
+				if a == b {
+					continue // Loops back to the same node aren't drawn
+				}
+				f.edges = append(f.edges, &flowEdge{
+					from:   a,
+					to:     b,
+					label:  mermaidText(link[4]),
+					arrow:  link[3] != "",
+					dotted: strings.Contains(link[2], "."),
+					hidden: strings.HasPrefix(link[2], "~"),
+				})
+			}
+		}
+		from = to
+	}
+	return true
+}
+
+// parseNodes reads a node, or several joined by &, from the start of s.
+func (f *flowchart) parseNodes(s string) (nodes []*flowNode, rest string, ok bool) {
+	for {
+		var node *flowNode
+		if node, s, ok = f.parseNode(s); !ok {
+			return nil, s, false
+		}
+		nodes = append(nodes, node)
+
+		trimmed := strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(trimmed, "&") {
+			return nodes, s, true
+		}
+		s = trimmed[1:]
+	}
+}
+
+// parseNode reads a node ID and its optional shape and text from the start
+// of s.
+func (f *flowchart) parseNode(s string) (*flowNode, string, bool) {
+	s = strings.TrimLeft(s, " \t")
+	id := flowIDPattern.FindString(s)
+	if id == "" {
+		return nil, s, false
+	}
+	s = s[len(id):]
+
+	node := f.byID[id]
+	if node == nil {
+		node = &flowNode{id: id, label: id}
+		f.byID[id] = node
+		f.nodes = append(f.nodes, node)
+	}
+
+	for _, shape := range flowShapes {
+		if !strings.HasPrefix(s, shape.open) {
+			continue
+		}
+		body := s[len(shape.open):]
+		end := strings.Index(body, shape.close)
+		if strings.HasPrefix(body, `"`) {
+			// Quoted text can contain brackets
+			quote := strings.Index(body[1:], `"`) + 2
+			if quote == 1 || !strings.HasPrefix(body[quote:], shape.close) {
+				continue
+			}
+			end = quote
+		}
+		if end < 0 {
+			continue
+		}
+		node.label, node.rounded = mermaidText(body[:end]), shape.rounded
+		s = body[end+len(shape.close):]
+		break
+	}
+	return node, flowClassPattern.ReplaceAllString(s, ""), true
+}
+
+// layout ranks the nodes, splits the edges into hops between adjacent ranks
+// and returns the nodes of each rank, in drawing order.
+func (f *flowchart) layout() ([][]*flowNode, []flowHop) {
+	// Edges that close a cycle are found depth first and reversed
+	outgoing := make(map[*flowNode][]*flowEdge)
+	for _, e := range f.edges {
+		outgoing[e.from] = append(outgoing[e.from], e)
+	}
+	state := make(map[*flowNode]int) // 1 while visiting, 2 when done
+	var visit func(n *flowNode)
+	visit = func(n *flowNode) {
+		state[n] = 1
+		for _, e := range outgoing[n] {
+			switch state[e.to] {
+			case 0:
+				visit(e.to)
+			case 1:
+				e.reversed = true
+			}
+		}
+		state[n] = 2
+	}
+	for _, n := range f.nodes {
+		if state[n] == 0 {
+			visit(n)
+		}
+	}
+
+	// Each node goes one rank below the lowest node pointing to it
+	for changed := true; changed; {
+		changed = false
+		for _, e := range f.edges {
+			if upper, lower := e.ends(); lower.rank <= upper.rank {
+				lower.rank = upper.rank + 1
+				changed = true
+			}
+		}
+	}
+
+	nodes := slices.Clone(f.nodes)
+	var hops []flowHop
+	for _, e := range f.edges {
+		upper, lower := e.ends()
+		prev := upper
+		for rank := upper.rank + 1; rank <= lower.rank; rank++ {
+			next := lower
+			if rank < lower.rank {
+				next = &flowNode{dummy: true, rank: rank}
+				nodes = append(nodes, next)
+			}
+			hops = append(hops, flowHop{edge: e, from: prev, to: next, first: prev == upper, last: next == lower})
+			prev.below = append(prev.below, next)
+			next.above = append(next.above, prev)
+			prev = next
+		}
+	}
+
+	var ranks [][]*flowNode
+	for _, n := range nodes {
+		for len(ranks) <= n.rank {
+			ranks = append(ranks, nil)
+		}
+		ranks[n.rank] = append(ranks[n.rank], n)
+	}
+
+	// Order each rank by the average position of the nodes above, which
+	// keeps edges from crossing
+	for sweep := 0; sweep < 2; sweep++ {
+		for r := 1; r < len(ranks); r++ {
+			position := make(map[*flowNode]int)
+			for i, n := range ranks[r-1] {
+				position[n] = i
+			}
+			key := make(map[*flowNode]float64)
+			for i, n := range ranks[r] {
+				key[n] = float64(i)
+				if len(n.above) > 0 {
+					sum := 0
+					for _, a := range n.above {
+						sum += position[a]
+					}
+					key[n] = float64(sum) / float64(len(n.above))
+				}
+			}
+			sort.SliceStable(ranks[r], func(i, j int) bool {
+				return key[ranks[r][i]] < key[ranks[r][j]]
+			})
+		}
+	}
+
+	return ranks, hops
+}
+
+// place positions the nodes of a rank across it in order, each as close to
+// its wanted center as the nodes before it allow.
+func place(rank []*flowNode, want map[*flowNode]int, gap int) {
+	next := 0
+	for _, n := range rank {
+		n.across = next
+		if center, ok := want[n]; ok && center-n.size/2 > next {
+			n.across = center - n.size/2
+		}
+		next = n.across + n.size + gap
+	}
+}
+
+// neighbourCenters returns the average center of the nodes each node of a
+// rank is joined to in the rank above (up) or below, keeping the current
+// center of nodes without such neighbours.
+func neighbourCenters(rank []*flowNode, up bool) map[*flowNode]int {
+	want := make(map[*flowNode]int)
+	for _, n := range rank {
+		neighbours := n.below
+		if up {
+			neighbours = n.above
+		}
+		want[n] = n.center()
+		if len(neighbours) > 0 {
+			sum := 0
+			for _, neighbour := range neighbours {
+				sum += neighbour.center()
+			}
+			want[n] = sum / len(neighbours)
+		}
+	}
+	return want
+}
+
+// draw lays out the chart and draws it.
+func (f *flowchart) draw() string {
+	ranks, hops := f.layout()
+
+	gap := 3 // Between nodes of a rank
+	if f.horizontal {
+		gap = 1
+	}
+	for _, n := range slices.Concat(ranks...) {
+		width := runewidth.StringWidth(n.label) + 4
+		switch {
+		case n.dummy:
+			n.size = 1
+		case f.horizontal:
+			n.size, n.length = 3, width
+		default:
+			n.size, n.length = width, 3
+		}
+	}
+
+	// Nodes are centered on their neighbours above, then on those below
+	for i, rank := range ranks {
+		want := map[*flowNode]int{}
+		if i > 0 {
+			want = neighbourCenters(rank, true)
+		}
+		place(rank, want, gap)
+	}
+	for i := len(ranks) - 2; i >= 0; i-- {
+		place(ranks[i], neighbourCenters(ranks[i], false), gap)
+	}
+
+	// Reversed edges meet wide nodes off center, so they can be told apart
+	// from the edges leaving and entering them
+	attach := func(n *flowNode, e *flowEdge) int {
+		if e.reversed && !n.dummy && n.size >= 7 {
+			return n.center() + 2
+		}
+		return n.center()
+	}
+	for i := range hops {
+		hops[i].fromAt = attach(hops[i].from, hops[i].edge)
+		hops[i].toAt = attach(hops[i].to, hops[i].edge)
+	}
+
+	// Between ranks there is a row for edges leaving nodes, one for each
+	// track edges run across the ranks on, room for labels and a row for
+	// arrowheads
+	starts := make([]int, len(ranks))
+	lengths := make([]int, len(ranks))
+	tracks := make(map[*flowHop]int)
+	labelAt := make([]int, len(ranks))
+	leaving := make([][]*flowHop, len(ranks)) // Hops by the rank they leave
+	for i := range hops {
+		leaving[hops[i].from.rank] = append(leaving[hops[i].from.rank], &hops[i])
+	}
+	along := 0
+	for r, rank := range ranks {
+		starts[r] = along
+		lengths[r] = 1
+		for _, n := range rank {
+			lengths[r] = max(lengths[r], n.length)
+		}
+		along += lengths[r]
+		if r == len(ranks)-1 {
+			break
+		}
+
+		var assigned [][]*flowHop
+		labels := 0
+		for _, h := range leaving[r] {
+			if h.first && h.edge.label != "" {
+				labels = max(labels, runewidth.StringWidth(h.edge.label)+2)
+			}
+			if h.fromAt == h.toAt {
+				continue
+			}
+			t := 0
+			for t < len(assigned) && slices.ContainsFunc(assigned[t], func(a *flowHop) bool { return !canShareTrack(a, h) }) {
+				t++
+			}
+			if t == len(assigned) {
+				assigned = append(assigned, nil)
+			}
+			assigned[t] = append(assigned[t], h)
+			tracks[h] = t
+		}
+
+		labelAt[r] = along + 1 + len(assigned)
+		if !f.horizontal && labels > 0 {
+			labels = 1
+		}
+		along += 1 + len(assigned) + labels + 1
+	}
+
+	c := &canvas{}
+	for r, rank := range ranks {
+		for _, n := range rank {
+			if n.dummy {
+				continue
+			}
+			x, y := f.xy(starts[r], n.across)
+			if f.horizontal {
+				c.box(x, y, n.length, n.size, n.label, n.rounded)
+			} else {
+				c.box(x, y, n.size, n.length, n.label, n.rounded)
+			}
+		}
+	}
+
+	joints := []rune("┬┴├┤")
+	if glyphs.ascii {
+		joints = []rune("++++")
+	}
+	forward, backward := linkDown, linkUp
+	leave, enter := joints[0], joints[1]
+	if f.horizontal {
+		forward, backward = linkRight, linkLeft
+		leave, enter = joints[2], joints[3]
+	}
+
+	for i := range hops {
+		h := &hops[i]
+		if h.edge.hidden {
+			continue
+		}
+		r := h.from.rank
+		start := starts[r] + h.from.length
+		end := starts[r+1] - 1
+		from, to := h.fromAt, h.toAt
+		dotted := h.edge.dotted
+
+		if from == to {
+			f.line(c, start, from, end, to, dotted)
+		} else {
+			track := starts[r] + lengths[r] + 1 + tracks[h]
+			f.line(c, start, from, track, from, dotted)
+			f.line(c, track, from, track, to, dotted)
+			f.line(c, track, to, end, to, dotted)
+		}
+
+		arrowAtStart := h.edge.arrow && h.edge.reversed && h.first
+		arrowAtEnd := h.edge.arrow && !h.edge.reversed && h.last
+		if !h.from.dummy {
+			if arrowAtStart {
+				f.put(c, start, from, arrowGlyph(backward))
+			} else {
+				f.put(c, start-1, from, leave)
+			}
+		}
+		if !h.to.dummy {
+			if arrowAtEnd {
+				f.put(c, end, to, arrowGlyph(forward))
+			} else {
+				f.put(c, end+1, to, enter)
+			}
+		}
+
+		if h.first && h.edge.label != "" {
+			f.label(c, labelAt[r], to, h.edge.label)
+		}
+	}
+
+	return c.String()
+}
+
+// canShareTrack reports whether two hops can run across the ranks on the
+// same track: they join where they meet, or don't meet.
+func canShareTrack(a, b *flowHop) bool {
+	if (a.from == b.from && a.fromAt == b.fromAt) || (a.to == b.to && a.toAt == b.toAt) {
+		return true
+	}
+	aLow, aHigh := min(a.fromAt, a.toAt), max(a.fromAt, a.toAt)
+	bLow, bHigh := min(b.fromAt, b.toAt), max(b.fromAt, b.toAt)
+	return aHigh+1 < bLow || bHigh+1 < aLow
+}
+
+// xy converts a position along and across the ranks to canvas coordinates.
+func (f *flowchart) xy(along, across int) (int, int) {
+	if f.horizontal {
+		return along, across
+	}
+	return across, along
+}
+
+func (f *flowchart) put(c *canvas, along, across int, r rune) {
+	x, y := f.xy(along, across)
+	c.put(x, y, r)
+}
+
+// line draws a straight line along or across the ranks.
+func (f *flowchart) line(c *canvas, along1, across1, along2, across2 int, dotted bool) {
+	x1, y1 := f.xy(along1, across1)
+	x2, y2 := f.xy(along2, across2)
+	if (across1 == across2) != f.horizontal {
+		c.vline(x1, y1, y2, dotted)
+	} else {
+		c.hline(x1, x2, y1, dotted)
+	}
+}
+
+// label writes the text of an edge next to where it enters its target:
+// beside the line top-down, above it left-right. Another side is tried if
+// the space is taken.
+func (f *flowchart) label(c *canvas, along, across int, text string) {
+	width := runewidth.StringWidth(text)
+	var spots [][2]int
+	if f.horizontal {
+		spots = [][2]int{{along + 1, across - 1}, {along + 1, across + 1}}
+	} else {
+		spots = [][2]int{{across + 2, along}, {across - 1 - width, along}}
+	}
+	for _, spot := range spots {
+		if spot[0] >= 0 && spot[1] >= 0 && c.free(spot[0], spot[1], text) {
+			c.write(spot[0], spot[1], text)
+			return
+		}
+	}
+	c.write(spots[0][0], spots[0][1], text)
+}
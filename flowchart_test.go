@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderFlowchartLeftRight(t *testing.T) {
+	got, ok := renderMermaid("graph LR\n  A[Start] -->|go| B(End)")
+	if !ok {
+		t.Fatal("Expected the flowchart to be drawn")
+	}
+	expected := strings.Join([]string{
+		"┌───────┐  go  ╭─────╮",
+		"│ Start ├─────▶│ End │",
+		"└───────┘      ╰─────╯",
+	}, "\n")
+	if got != expected {
+		t.Errorf("Unexpected drawing:\n%s\n\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRenderFlowchart(t *testing.T) {
+	source := strings.Join([]string{
+		"flowchart TD",
+		"  %% A review loop",
+		`  A["Write [draft]"] --> B{Review}`,
+		"  B -- approved --> C([Publish]):::done",
+		"  B -.->|changes| A",
+		"  C ==> D & E",
+		"  subgraph Later",
+		"    E[Extra]",
+		"  end",
+		"  classDef done fill:#9f6",
+	}, "\n")
+	got, ok := renderMermaid(source)
+	if !ok {
+		t.Fatal("Expected the flowchart to be drawn")
+	}
+
+	lines := strings.Split(got, "\n")
+	for _, text := range []string{"│ Write [draft] │", "│ Review │", "│ Publish │", "approved", "changes", "│ D │", "│ Extra │"} {
+		if !strings.Contains(got, text) {
+			t.Errorf("Expected %q in the drawing:\n%s", text, got)
+		}
+	}
+
+	// Ranks go down the page in order
+	row := func(text string) int {
+		for i, line := range lines {
+			if strings.Contains(line, text) {
+				return i
+			}
+		}
+		return -1
+	}
+	if !(row("Write") < row("Review") && row("Review") < row("Publish") && row("Publish") < row("│ D │")) {
+		t.Errorf("Expected the nodes in rank order:\n%s", got)
+	}
+	if row("│ D │") != row("│ Extra │") {
+		t.Errorf("Expected D and E in the same rank:\n%s", got)
+	}
+
+	// The edge back to the draft points up into it, dotted
+	if !strings.Contains(got, "▲") || !strings.Contains(got, "┆") {
+		t.Errorf("Expected a dotted edge pointing up:\n%s", got)
+	}
+	if strings.Count(got, "▼") != 4 {
+		t.Errorf("Expected 4 edges pointing down, got:\n%s", got)
+	}
+}
+
+func TestRenderFlowchartASCII(t *testing.T) {
+	defer func(g glyphSet) { glyphs = g }(glyphs)
+	glyphs = asciiGlyphs
+
+	got, ok := renderMermaid("graph TD; A-->B; A-->C; B-->D; C-->D; A-->D")
+	if !ok {
+		t.Fatal("Expected the flowchart to be drawn")
+	}
+	for _, r := range got {
+		if r > 127 {
+			t.Fatalf("Expected only ASCII, got %q in:\n%s", r, got)
+		}
+	}
+	if strings.Count(got, "v") != 3 {
+		t.Errorf("Expected 3 arrowheads where the edges meet, got:\n%s", got)
+	}
+}
+
+func TestParseFlowchart(t *testing.T) {
+	f := &flowchart{byID: make(map[string]*flowNode)}
+	for _, statement := range []string{
+		"api-gateway[API Gateway]-->db[(Database)]",
+		`a & b --> c{{"Hexagon <br> text"}}`,
+		"c == done ==> d>Flag]",
+		"d <--> a",
+		"d ~~~ b",
+	} {
+		if !f.parse(statement) {
+			t.Fatalf("Failed to parse %q", statement)
+		}
+	}
+
+	labels := map[string]string{
+		"api-gateway": "API Gateway",
+		"db":          "Database",
+		"c":           "Hexagon text",
+		"d":           "Flag",
+		"a":           "a",
+	}
+	for id, label := range labels {
+		if node := f.byID[id]; node == nil || node.label != label {
+			t.Errorf("Expected node %s labelled %q, got %+v", id, label, node)
+		}
+	}
+	if !f.byID["db"].rounded || f.byID["api-gateway"].rounded {
+		t.Error("Expected only the database to be rounded")
+	}
+
+	if len(f.edges) != 6 {
+		t.Fatalf("Expected 6 edges, got %d", len(f.edges))
+	}
+	if e := f.edges[3]; e.from.id != "c" || e.to.id != "d" || e.label != "done" || !e.arrow {
+		t.Errorf("Unexpected edge with text: %+v", e)
+	}
+	if e := f.edges[5]; !e.hidden || e.arrow {
+		t.Errorf("Expected an invisible link: %+v", e)
+	}
+
+	if f.parse("a --> ") || f.parse("--> b") {
+		t.Error("Expected incomplete links to fail")
+	}
+}
+
+func TestRenderFlowchartTooLarge(t *testing.T) {
+	chart := func(nodes int) string {
+		lines := []string{"graph TD"}
+		for i := 1; i < nodes; i++ {
+			lines = append(lines, fmt.Sprintf("  A --> N%d", i))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	if _, ok := renderMermaid(chart(maxFlowNodes)); !ok {
+		t.Errorf("Expected a chart of %d nodes to be drawn", maxFlowNodes)
+	}
+	if _, ok := renderMermaid(chart(maxFlowNodes + 1)); ok {
+		t.Errorf("Expected a chart of more than %d nodes to keep its source", maxFlowNodes)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// metaField is a top level key of a document's front matter and its value
+// formatted for display; lists are joined with commas.
+type metaField struct {
+	key   string
+	value string
+}
+
+// frontMatter is the YAML (---) or TOML (+++) metadata block at the start of
+// a document, as used by Hugo and Jekyll.
+type frontMatter struct {
+	fields []metaField
+	lines  int // Source lines taken up by the block, delimiters included
+}
+
+// get returns the value of a field, or "".
+func (f frontMatter) get(key string) string {
+	for _, field := range f.fields {
+		if strings.EqualFold(field.key, key) {
+			return field.value
+		}
+	}
+	return ""
+}
+
+// headerFields are the front matter fields shown above a rendered document,
+// in this order. Other fields are only visible in the raw view.
+var headerFields = []string{"title", "description", "author", "authors", "date", "lastmod", "updated", "tags", "categories", "draft"}
+
+// splitFrontMatter separates the front matter of a document from its body.
+// ok is false if the document has none.
+func splitFrontMatter(content string) (fm frontMatter, body string, ok bool) {
+	lines := strings.Split(content, "\n")
+	delimiter := strings.TrimRight(lines[0], "\r")
+	if delimiter != "---" && delimiter != "+++" {
+		return frontMatter{}, content, false
+	}
+
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if line == delimiter || (delimiter == "---" && line == "...") {
+			block := lines[1:i]
+			if delimiter == "+++" {
+				fm.fields = parseTOMLFields(block)
+			} else {
+				fm.fields = parseYAMLFields(block)
+			}
+			fm.lines = i + 1
+			return fm, strings.Join(lines[i+1:], "\n"), true
+		}
+	}
+
+	// An unclosed block is a horizontal rule, not front matter
+	return frontMatter{}, content, false
+}
+
+// parseYAMLFields reads the top level keys of a YAML block. Nested mappings
+// are skipped; block lists and multi-line strings are joined into one value.
+func parseYAMLFields(lines []string) []metaField {
+	var fields []metaField
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if line == "" || strings.HasPrefix(line, "#") || startsIndented(line) {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		// Collect the indented lines that belong to the key
+		var items []string
+		for i+1 < len(lines) && (startsIndented(lines[i+1]) || strings.HasPrefix(lines[i+1], "- ")) {
+			i++
+			items = append(items, strings.TrimSpace(strings.TrimRight(lines[i], "\r")))
+		}
+
+		switch {
+		case value == "" && len(items) > 0 && strings.HasPrefix(items[0], "- "):
+			var list []string
+			for _, item := range items {
+				if rest, ok := strings.CutPrefix(item, "- "); ok {
+					list = append(list, unquote(rest))
+				}
+			}
+			value = strings.Join(list, ", ")
+		case value == "" && len(items) > 0:
+			continue // A nested mapping
+		case strings.TrimRight(value, "+-") == "|" || strings.TrimRight(value, "+-") == ">":
+			value = strings.Join(items, " ")
+		default:
+			value = formatValue(value)
+		}
+		fields = append(fields, metaField{key: strings.TrimSpace(key), value: value})
+	}
+	return fields
+}
+
+// parseTOMLFields reads the top level keys of a TOML block, up to its first
+// table. Arrays can span lines until their brackets are closed.
+func parseTOMLFields(lines []string) []metaField {
+	var fields []metaField
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "[") {
+			break // Keys from here on belong to a table
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.HasPrefix(line, "#") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "[") {
+			depth := strings.Count(value, "[") - strings.Count(value, "]")
+			for ; depth > 0 && i+1 < len(lines); i++ {
+				next := strings.TrimSpace(lines[i+1])
+				if strings.HasPrefix(next, "#") {
+					continue
+				}
+				depth += strings.Count(next, "[") - strings.Count(next, "]")
+				value += " " + next
+			}
+		}
+		fields = append(fields, metaField{key: unquote(strings.TrimSpace(key)), value: formatValue(value)})
+	}
+	return fields
+}
+
+// formatValue formats a scalar or an inline list such as [a, "b"].
+func formatValue(value string) string {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		var list []string
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = unquote(strings.TrimSpace(item)); item != "" {
+				list = append(list, item)
+			}
+		}
+		return strings.Join(list, ", ")
+	}
+	return unquote(value)
+}
+
+// unquote removes the quotes around a string value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func startsIndented(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// metadataHeader formats the header fields of front matter as a block quote
+// to render above the document, or returns "" if it has none of them.
+func metadataHeader(fm frontMatter) string {
+	var lines []string
+	for _, key := range headerFields {
+		if value := fm.get(key); value != "" {
+			lines = append(lines, "> **"+capitalize(key)+":** "+escapeMarkdown(value))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "  \n") + "\n\n"
+}
+
+// metaMessage reports the front matter header being toggled.
+func metaMessage(hidden bool) string {
+	if hidden {
+		return "Front matter hidden"
+	}
+	return "Front matter shown"
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// escapeMarkdown keeps text from being read as markdown syntax.
+func escapeMarkdown(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune("\\`*_[]<>#|~", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSplitFrontMatterYAML(t *testing.T) {
+	content := strings.Join([]string{
+		"---",
+		"title: \"Release notes: v2\"",
+		"date: 2024-03-01T10:00:00Z",
+		"tags:",
+		"  - go",
+		"  - 'cli'",
+		"params:",
+		"  weight: 3",
+		"description: >",
+		"  Everything that",
+		"  changed.",
+		"# A comment, not a heading",
+		"---",
+		"# Body",
+	}, "\n")
+
+	fm, body, ok := splitFrontMatter(content)
+	if !ok {
+		t.Fatal("Expected front matter to be found")
+	}
+	if body != "# Body" || fm.lines != 13 {
+		t.Errorf("Expected the body after line 13, got %q (%d lines)", body, fm.lines)
+	}
+
+	expected := map[string]string{
+		"title":       "Release notes: v2",
+		"date":        "2024-03-01T10:00:00Z",
+		"tags":        "go, cli",
+		"params":      "",
+		"description": "Everything that changed.",
+	}
+	for key, value := range expected {
+		if got := fm.get(key); got != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got)
+		}
+	}
+
+	// Headings start after the front matter
+	headings := ParseHeadings(content)
+	if len(headings) != 1 || headings[0].Text != "Body" || headings[0].Line != 13 {
+		t.Errorf("Expected only the body's heading, got %+v", headings)
+	}
+}
+
+func TestSplitFrontMatterTOML(t *testing.T) {
+	content := "+++\ntitle = 'Hugo page'\ntags = [\"a\", \"b\"]\ndraft = true\n\n[menu]\nweight = 1\n+++\nText\n"
+
+	fm, body, ok := splitFrontMatter(content)
+	if !ok {
+		t.Fatal("Expected front matter to be found")
+	}
+	if body != "Text\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+	if fm.get("title") != "Hugo page" || fm.get("tags") != "a, b" || fm.get("draft") != "true" || fm.get("weight") != "" {
+		t.Errorf("Unexpected fields %+v", fm.fields)
+	}
+}
+
+func TestSplitFrontMatterTOMLMultiLineArray(t *testing.T) {
+	content := "+++\ntitle = 'Hugo page'\ntags = [\n  \"a\",\n  # A comment\n  \"b\",\n]\ndraft = true\n\n[menu]\nweight = 1\n+++\nText\n"
+
+	fm, _, ok := splitFrontMatter(content)
+	if !ok {
+		t.Fatal("Expected front matter to be found")
+	}
+	if fm.get("tags") != "a, b" || fm.get("draft") != "true" || fm.get("weight") != "" {
+		t.Errorf("Unexpected fields %+v", fm.fields)
+	}
+}
+
+func TestSplitFrontMatterNone(t *testing.T) {
+	for _, content := range []string{"# Title\n---\n", "---\nA rule without an end\n", "Text\n+++\n"} {
+		if _, body, ok := splitFrontMatter(content); ok || body != content {
+			t.Errorf("Expected no front matter in %q", content)
+		}
+	}
+}
+
+func TestPrepareForRenderFrontMatter(t *testing.T) {
+	content := "---\ntitle: Post *one*\ntags: [a, b]\nlayout: post\n---\nBody\n"
+
+	expected := "> **Title:** Post \\*one\\*  \n> **Tags:** a, b\n\nBody\n"
+	if got := prepareForRender("post.md", content, true); got != expected {
+		t.Errorf("Expected header\n%q\ngot\n%q", expected, got)
+	}
+	if got := prepareForRender("post.md", content, false); got != "Body\n" {
+		t.Errorf("Expected just the body, got %q", got)
+	}
+}
+
+func TestDualPaneToggleMeta(t *testing.T) {
+	m := &DualPaneModel{
+		style:          "ascii",
+		width:          100,
+		height:         20,
+		splitRatio:     0.3,
+		currentContent: "---\ntitle: Hello\n---\nBody text\n",
+	}
+	m.updateRendererWidth()
+	if !strings.Contains(strings.Join(m.renderedLines, "\n"), "Title:") {
+		t.Fatalf("Expected the front matter header, got %q", m.renderedLines)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	if strings.Contains(strings.Join(m.renderedLines, "\n"), "Title:") || m.statusMessage != "Front matter hidden" {
+		t.Errorf("Expected m to hide the header, got %q (%q)", m.renderedLines, m.statusMessage)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// Scoring constants follow fzf's algorithm: every matched character scores,
+// gaps are penalized and characters at word boundaries earn a bonus.
+const (
+	fuzzyScoreMatch        = 16
+	fuzzyScoreGapStart     = -3
+	fuzzyScoreGapExtension = -1
+
+	fuzzyBonusBoundary          = fuzzyScoreMatch / 2
+	fuzzyBonusBoundaryWhite     = fuzzyBonusBoundary + 2
+	fuzzyBonusBoundaryDelimiter = fuzzyBonusBoundary + 1
+	fuzzyBonusNonWord           = fuzzyScoreMatch / 2
+	fuzzyBonusCamel123          = fuzzyBonusBoundary + fuzzyScoreGapExtension
+	fuzzyBonusConsecutive       = -(fuzzyScoreGapStart + fuzzyScoreGapExtension)
+	fuzzyBonusFirstCharFactor   = 2
+)
+
+type charClass int
+
+const (
+	charWhite charClass = iota
+	charNonWord
+	charDelimiter
+	charLower
+	charUpper
+	charLetter
+	charNumber
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsDigit(r):
+		return charNumber
+	case unicode.IsLetter(r):
+		return charLetter
+	case unicode.IsSpace(r):
+		return charWhite
+	case strings.ContainsRune("/,:;|", r):
+		return charDelimiter
+	}
+	return charNonWord
+}
+
+// boundaryBonus returns the bonus for matching a character of class cur that
+// follows a character of class prev.
+func boundaryBonus(prev, cur charClass) int {
+	if cur > charNonWord && cur != charDelimiter {
+		switch prev {
+		case charWhite:
+			return fuzzyBonusBoundaryWhite
+		case charDelimiter:
+			return fuzzyBonusBoundaryDelimiter
+		case charNonWord:
+			return fuzzyBonusBoundary
+		}
+	}
+
+	if prev == charLower && cur == charUpper || prev != charNumber && cur == charNumber {
+		return fuzzyBonusCamel123
+	}
+
+	switch cur {
+	case charNonWord, charDelimiter:
+		return fuzzyBonusNonWord
+	case charWhite:
+		return fuzzyBonusBoundaryWhite
+	}
+	return 0
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in order in
+// text and scores the match the way fzf does. Positions are the rune indices
+// of the matched characters. Patterns without upper case letters ignore case.
+func fuzzyMatch(pattern, text string) (int, []int, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	fold := smartCase(pattern)
+	equal := func(a, b rune) bool {
+		if fold {
+			return unicode.ToLower(a) == unicode.ToLower(b)
+		}
+		return a == b
+	}
+
+	// Forward pass: find where the first complete match ends
+	pidx, end := 0, -1
+	for i, r := range t {
+		if equal(r, p[pidx]) {
+			pidx++
+			if pidx == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward pass: find the tightest start for that end
+	pidx, start := len(p)-1, 0
+	for i := end; i >= 0; i-- {
+		if equal(t[i], p[pidx]) {
+			pidx--
+			if pidx < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	// Score the match in [start, end]
+	score, consecutive, firstBonus := 0, 0, 0
+	inGap := false
+	prevClass := charWhite
+	if start > 0 {
+		prevClass = classOf(t[start-1])
+	}
+
+	positions := make([]int, 0, len(p))
+	pidx = 0
+	for i := start; i <= end; i++ {
+		class := classOf(t[i])
+		if pidx < len(p) && equal(t[i], p[pidx]) {
+			positions = append(positions, i)
+			score += fuzzyScoreMatch
+			bonus := boundaryBonus(prevClass, class)
+			if consecutive == 0 {
+				firstBonus = bonus
+			} else {
+				if bonus >= fuzzyBonusBoundary && bonus > firstBonus {
+					firstBonus = bonus
+				}
+				bonus = max(bonus, max(firstBonus, fuzzyBonusConsecutive))
+			}
+			if pidx == 0 {
+				score += bonus * fuzzyBonusFirstCharFactor
+			} else {
+				score += bonus
+			}
+			inGap = false
+			consecutive++
+			pidx++
+		} else {
+			if inGap {
+				score += fuzzyScoreGapExtension
+			} else {
+				score += fuzzyScoreGapStart
+			}
+			inGap = true
+			consecutive = 0
+			firstBonus = 0
+		}
+		prevClass = class
+	}
+
+	return score, positions, true
+}
+
+// fuzzyResult is a ranked candidate of the fuzzy finder.
+type fuzzyResult struct {
+	index     int    // Index into the candidate list
+	text      string // Text that was matched
+	path      string // File the candidate stands for, set by the finder
+	score     int
+	positions []int
+}
+
+// fuzzyRank matches pattern against all candidates and returns the matches
+// best first. Ties go to the shorter candidate, then alphabetical order.
+func fuzzyRank(pattern string, candidates []string) []fuzzyResult {
+	var results []fuzzyResult
+	for i, candidate := range candidates {
+		if score, positions, ok := fuzzyMatch(pattern, candidate); ok {
+			results = append(results, fuzzyResult{index: i, text: candidate, score: score, positions: positions})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.text) != len(b.text) {
+			return len(a.text) < len(b.text)
+		}
+		return a.text < b.text
+	})
+
+	return results
+}
+
+// fuzzyFinder is the Ctrl+P popup that jumps to a file by fuzzy matching its
+// path relative to the tree root.
+type fuzzyFinder struct {
+	active     bool
+	query      string
+	candidates []string // Relative paths, parallel to paths
+	paths      []string
+	results    []fuzzyResult
+	cursor     int
+	offset     int
+}
+
+// open shows the finder over files, displayed relative to root.
+func (f *fuzzyFinder) open(files []string, root string) {
+	f.active = true
+	f.query = ""
+	f.candidates = make([]string, len(files))
+	f.paths = slices.Clone(files)
+	for i, file := range files {
+		relPath, err := filepath.Rel(root, file)
+		if err != nil {
+			relPath = file
+		}
+		f.candidates[i] = relPath
+	}
+	f.rank()
+}
+
+func (f *fuzzyFinder) rank() {
+	f.results = fuzzyRank(f.query, f.candidates)
+	for i := range f.results {
+		f.results[i].path = f.paths[f.results[i].index]
+	}
+	f.cursor = 0
+	f.offset = 0
+}
+
+// handleKey applies a key press to the finder. It returns the path of the
+// chosen file, or "" if no file was chosen. Files may be found while the
+// finder is open, so the path is returned rather than an index.
+func (f *fuzzyFinder) handleKey(msg tea.KeyMsg, visible int) string {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		f.active = false
+	case "enter":
+		f.active = false
+		if f.cursor < len(f.results) {
+			return f.results[f.cursor].path
+		}
+	case "up", "ctrl+k", "ctrl+p":
+		if f.cursor > 0 {
+			f.cursor--
+		}
+	case "down", "ctrl+j", "ctrl+n":
+		if f.cursor < len(f.results)-1 {
+			f.cursor++
+		}
+	default:
+		if query, ok := editPrompt(f.query, msg); ok && query != f.query {
+			f.query = query
+			f.rank()
+		}
+	}
+
+	// Keep the cursor visible
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	} else if visible > 0 && f.cursor >= f.offset+visible {
+		f.offset = f.cursor - visible + 1
+	}
+	return ""
+}
+
+// view renders the popup box, at most width x height cells including the
+// border.
+func (f *fuzzyFinder) view(width, height int) string {
+	innerWidth := max(10, width-4)
+	visible := f.visibleRows(height)
+
+	matchStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230"))
+
+	var lines []string
+	lines = append(lines, truncateToWidth("> "+f.query+glyphs.inputCursor, innerWidth))
+	lines = append(lines, fmt.Sprintf("  %d/%d", len(f.results), len(f.candidates)))
+
+	for i := f.offset; i < len(f.results) && i < f.offset+visible; i++ {
+		result := f.results[i]
+		// Paths are cut at the start, keeping the file name
+		text, cut := truncateLeft(result.text, innerWidth-2)
+
+		if i == f.cursor {
+			lines = append(lines, selectedStyle.Render(glyphs.cursor+" "+text))
+			continue
+		}
+
+		// Highlight matched characters on unselected rows
+		matched := make(map[int]bool, len(result.positions))
+		for _, pos := range result.positions {
+			if cut == 0 {
+				matched[pos] = true
+			} else if pos >= cut {
+				matched[pos-cut+len("...")] = true
+			}
+		}
+		var b strings.Builder
+		b.WriteString("  ")
+		for j, r := range []rune(text) {
+			if matched[j] {
+				b.WriteString(matchStyle.Render(string(r)))
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		lines = append(lines, b.String())
+	}
+
+	for len(lines) < visible+2 {
+		lines = append(lines, "")
+	}
+
+	return lipgloss.NewStyle().
+		BorderStyle(glyphs.focusedBorder).
+		BorderForeground(lipgloss.Color("62")).
+		Width(innerWidth).
+		Render(strings.Join(lines, "\n"))
+}
+
+// truncateLeft shortens s to width cells by dropping runes from its start,
+// which are replaced by "...". It returns the number of runes dropped.
+func truncateLeft(s string, width int) (string, int) {
+	if width <= 3 || runewidth.StringWidth(s) <= width {
+		return s, 0
+	}
+
+	runes := []rune(s)
+	cut := len(runes)
+	for used := 3; cut > 0 && used+runewidth.RuneWidth(runes[cut-1]) <= width; cut-- {
+		used += runewidth.RuneWidth(runes[cut-1])
+	}
+	return "..." + string(runes[cut:]), cut
+}
+
+// visibleRows returns how many results fit in a popup of the given height,
+// which also holds the border, prompt and counter lines.
+func (f *fuzzyFinder) visibleRows(height int) int {
+	return max(1, height-4)
+}
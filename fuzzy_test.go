@@ -0,0 +1,191 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		matches bool
+	}{
+		{"rdme", "README.md", true},
+		{"dgm", "docs/guide/main.md", true},
+		{"", "anything", true},
+		{"xyz", "README.md", false},
+		{"Readme", "README.md", false}, // Upper case is exact
+		{"mdr", "README.md", false},    // Order matters
+	}
+
+	for _, test := range tests {
+		_, positions, ok := fuzzyMatch(test.pattern, test.text)
+		if ok != test.matches {
+			t.Errorf("fuzzyMatch(%q, %q) = %t, expected %t", test.pattern, test.text, ok, test.matches)
+		}
+		if ok && len(positions) != len([]rune(test.pattern)) {
+			t.Errorf("fuzzyMatch(%q, %q) returned %d positions", test.pattern, test.text, len(positions))
+		}
+	}
+
+	// Positions point at the matched characters
+	_, positions, _ := fuzzyMatch("gd", "docs/guide.md")
+	if len(positions) != 2 || positions[0] != 5 || positions[1] != 8 {
+		t.Errorf("Expected positions [5 8], got %v", positions)
+	}
+}
+
+func TestFuzzyRank(t *testing.T) {
+	candidates := []string{
+		"src/ext/tutorial/up.md",
+		"docs/setup.md",
+		"adr/0002-setup-ci.md",
+		"docs/deploy.md",
+	}
+
+	results := fuzzyRank("setup", candidates)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 matches, got %d", len(results))
+	}
+
+	// Consecutive matches at a word boundary win; the shorter path breaks ties
+	if results[0].text != "docs/setup.md" {
+		t.Errorf("Expected docs/setup.md to rank first, got %s", results[0].text)
+	}
+	if results[len(results)-1].text != "src/ext/tutorial/up.md" {
+		t.Errorf("Expected scattered match to rank last, got %s", results[len(results)-1].text)
+	}
+
+	// Matches at path boundaries beat matches inside words
+	results = fuzzyRank("api", []string{"rapid/notes.md", "docs/api.md"})
+	if results[0].text != "docs/api.md" {
+		t.Errorf("Expected docs/api.md to rank first, got %s", results[0].text)
+	}
+}
+
+func TestFuzzyFinderSelectsFile(t *testing.T) {
+	tempDir, _ := writeSearchFixture(t)
+	tree, err := FindMarkdownFiles(tempDir, true)
+	if err != nil {
+		t.Fatalf("FindMarkdownFiles failed: %v", err)
+	}
+
+	m := &DualPaneModel{
+		fileTree:   tree,
+		allFiles:   CollectFiles(tree),
+		rootPath:   tempDir,
+		width:      100,
+		height:     30,
+		splitRatio: 0.3,
+		raw:        true,
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	if !m.finder.active {
+		t.Fatal("Expected ctrl+p to open the finder")
+	}
+	if len(m.finder.results) != 3 {
+		t.Errorf("Expected all files listed for an empty query, got %d", len(m.finder.results))
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/c")})
+	if len(m.finder.results) == 0 || m.finder.results[0].text != "docs/c.md" {
+		t.Fatalf("Expected docs/c.md as best match, got %+v", m.finder.results)
+	}
+
+	// The popup is drawn over the panes
+	if !strings.Contains(ansi.Strip(m.View()), "> /c") {
+		t.Error("Expected finder prompt in the view")
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.finder.active {
+		t.Error("Expected finder to close after selection")
+	}
+	if m.selectedIndex != 1 {
+		t.Errorf("Expected selectedIndex 1, got %d", m.selectedIndex)
+	}
+	// docs/ and b.md come before it in the tree
+	if m.treeSelectedIdx != 2 {
+		t.Errorf("Expected treeSelectedIdx 2, got %d", m.treeSelectedIdx)
+	}
+	if !strings.Contains(m.currentContent, "# Gamma") {
+		t.Error("Expected the selected file to be loaded")
+	}
+}
+
+func TestPlaceOverlay(t *testing.T) {
+	bg := "aaaaaaaaaa\nbbbbbbbbbb\ncccccccccc"
+	result := placeOverlay(3, 1, "XX\nYY", bg)
+
+	expected := []string{"aaaaaaaaaa", "bbbXXbbbbb", "cccYYccccc"}
+	for i, line := range strings.Split(ansi.Strip(result), "\n") {
+		if line != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], line)
+		}
+	}
+}
+
+func TestFuzzyFinderSelectsFileFoundDuringWalk(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		"b.md": "# Beta",
+		"c.md": "# Gamma",
+	})
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+	m.width, m.height = 100, 30
+	runScan(t, m)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+
+	// A file sorted before the chosen one turns up while the finder is open
+	early := filepath.Join(tempDir, "a.md")
+	if err := os.WriteFile(early, []byte("# Alpha"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.Update(walkBatchMsg{id: m.walk.id, entries: []walkEntry{{path: early}}})
+	if m.fileIndex(early) >= 0 || !m.walkScheduled {
+		t.Fatalf("Expected a.md to wait for the next rebuild, got %v", m.allFiles)
+	}
+	m.Update(walkRebuildMsg{})
+	if m.fileIndex(early) != 0 {
+		t.Fatalf("Expected a.md to be inserted first, got %v", m.allFiles)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if expected := filepath.Join(tempDir, "c.md"); m.loadedPath != expected {
+		t.Errorf("Expected %s to be loaded, got %s", expected, m.loadedPath)
+	}
+	if !strings.Contains(m.currentContent, "# Gamma") {
+		t.Error("Expected the chosen file's content")
+	}
+}
+
+func TestFuzzyFinderKeepsFileNames(t *testing.T) {
+	if text, cut := truncateLeft("docs/guides/setup.md", 12); text != ".../setup.md" || cut != 11 {
+		t.Errorf("Expected the start of the path to be cut, got %q (%d)", text, cut)
+	}
+	if text, cut := truncateLeft("setup.md", 12); text != "setup.md" || cut != 0 {
+		t.Errorf("Expected a short path to be kept, got %q (%d)", text, cut)
+	}
+
+	var f fuzzyFinder
+	f.open([]string{"/repo/services/payments/internal/docs/refunds.md", "/repo/README.md"}, "/repo")
+	f.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("refunds")}, 5)
+	view := ansi.Strip(f.view(30, 10))
+	if !strings.Contains(view, "...ernal/docs/refunds.md") {
+		t.Errorf("Expected the matched file name to stay visible, got:\n%s", view)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// gitStatus is the state of a file in its git work tree.
+type gitStatus int
+
+const (
+	gitUnchanged gitStatus = iota
+	gitModified            // Changed in the work tree
+	gitStaged              // Changed in the index only
+	gitAdded               // New in the index, or since the base branch
+	gitUntracked
+)
+
+// marker is shown after the file's name in the tree.
+func (s gitStatus) marker() string {
+	switch s {
+	case gitModified:
+		return "[M]"
+	case gitStaged:
+		return "[S]"
+	case gitAdded:
+		return "[A]"
+	case gitUntracked:
+		return "[?]"
+	}
+	return ""
+}
+
+// gitStatusMsg delivers the status of the files in a work tree by absolute
+// path. changed holds the files changed relative to HEAD, or to the base
+// branch if one was given. id is the request it answers, so that the results
+// of an older request can be dropped.
+type gitStatusMsg struct {
+	id       int
+	statuses map[string]gitStatus
+	changed  map[string]bool
+	err      error
+}
+
+// loadGitStatus reads the git status of the work trees containing roots in
+// the background. Roots may be in different repositories; those outside any
+// are skipped. With a base branch, files committed since the branch forked
+// from it count as changed too.
+func loadGitStatus(id int, roots []string, base string) tea.Cmd {
+	return func() tea.Msg {
+		var workTrees []string
+		for _, root := range roots {
+			if workTree := findWorkTree(root); workTree != "" && !slices.Contains(workTrees, workTree) {
+				workTrees = append(workTrees, workTree)
+			}
+		}
+		if len(workTrees) == 0 {
+			return gitStatusMsg{id: id, err: fmt.Errorf("%s is not in a git work tree", strings.Join(roots, ", "))}
+		}
+
+		msg := gitStatusMsg{id: id, statuses: make(map[string]gitStatus), changed: make(map[string]bool)}
+		for _, workTree := range workTrees {
+			if err := readGitStatus(workTree, base, msg.statuses, msg.changed); err != nil {
+				return gitStatusMsg{id: id, err: err}
+			}
+		}
+		return msg
+	}
+}
+
+// readGitStatus adds the status of the files in a work tree to statuses,
+// and the files changed relative to HEAD or base to changed.
+func readGitStatus(workTree, base string, statuses map[string]gitStatus, changed map[string]bool) error {
+	out, err := runGit(workTree, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return err
+	}
+	for path, status := range parseGitStatus(workTree, out) {
+		statuses[path] = status
+		changed[path] = true
+	}
+
+	if base != "" {
+		mergeBase, err := runGit(workTree, "merge-base", base, "HEAD")
+		if err != nil {
+			return err
+		}
+		out, err := runGit(workTree, "diff", "--name-status", "-z", "--no-renames", strings.TrimSpace(string(mergeBase)))
+		if err != nil {
+			return err
+		}
+		for path, status := range parseGitDiff(workTree, out) {
+			changed[path] = true
+			if statuses[path] == gitUnchanged {
+				statuses[path] = status
+			}
+		}
+	}
+	return nil
+}
+
+// runGit runs a git command in dir and returns its output. Errors include
+// what git printed.
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
+
+// parseGitStatus parses the output of git status --porcelain=v1 -z.
+func parseGitStatus(workTree string, out []byte) map[string]gitStatus {
+	statuses := make(map[string]gitStatus)
+
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, path := entry[0], entry[1], entry[3:]
+		if x == 'R' || x == 'C' {
+			i++ // The next field is the original path
+		}
+
+		var status gitStatus
+		switch {
+		case x == '?':
+			status = gitUntracked
+		case x == 'A':
+			status = gitAdded
+		case y != ' ':
+			status = gitModified
+		default:
+			status = gitStaged
+		}
+		statuses[filepath.Join(workTree, filepath.FromSlash(path))] = status
+	}
+	return statuses
+}
+
+// parseGitDiff parses the output of git diff --name-status -z.
+func parseGitDiff(workTree string, out []byte) map[string]gitStatus {
+	statuses := make(map[string]gitStatus)
+
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := gitModified
+		if strings.HasPrefix(fields[i], "A") {
+			status = gitAdded
+		}
+		statuses[filepath.Join(workTree, filepath.FromSlash(fields[i+1]))] = status
+	}
+	return statuses
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// writeGitFixture creates a repository with a committed main branch and a
+// review branch that changes some of its documents.
+func writeGitFixture(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tempDir := writeFiles(t, map[string]string{
+		"README.md":       "# Readme",
+		"docs/guide.md":   "# Guide",
+		"docs/staged.md":  "# Staged",
+		"docs/branch.md":  "# Branch",
+		"docs/changed.md": "# Changed",
+	})
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+		if out, err := exec.Command("git", append([]string{"-C", tempDir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	git("add", ".")
+	git("commit", "-q", "-m", "Initial")
+
+	git("checkout", "-q", "-b", "review")
+	write("docs/branch.md", "# Branch\n\nCommitted on the branch")
+	write("docs/new.md", "# New")
+	git("add", ".")
+	git("commit", "-q", "-m", "Review")
+
+	write("docs/changed.md", "# Changed\n\nEdited")
+	write("docs/staged.md", "# Staged\n\nStaged")
+	git("add", "docs/staged.md")
+	write("docs/added.md", "# Added")
+	git("add", "docs/added.md")
+	write("docs/untracked.md", "# Untracked")
+	return tempDir
+}
+
+func TestLoadGitStatus(t *testing.T) {
+	tempDir := writeGitFixture(t)
+	path := func(name string) string { return filepath.Join(tempDir, filepath.FromSlash(name)) }
+
+	msg := loadGitStatus(0, []string{filepath.Join(tempDir, "docs")}, "")().(gitStatusMsg)
+	if msg.err != nil {
+		t.Fatalf("loadGitStatus failed: %v", msg.err)
+	}
+	expected := map[string]gitStatus{
+		"docs/changed.md":   gitModified,
+		"docs/staged.md":    gitStaged,
+		"docs/added.md":     gitAdded,
+		"docs/untracked.md": gitUntracked,
+	}
+	if len(msg.statuses) != len(expected) || len(msg.changed) != len(expected) {
+		t.Errorf("Expected %d changed files, got %v", len(expected), msg.statuses)
+	}
+	for name, status := range expected {
+		if msg.statuses[path(name)] != status {
+			t.Errorf("%s: expected status %s, got %s", name, status.marker(), msg.statuses[path(name)].marker())
+		}
+	}
+
+	// Against the base branch, committed changes count too
+	msg = loadGitStatus(0, []string{tempDir}, "main")().(gitStatusMsg)
+	if msg.err != nil {
+		t.Fatalf("loadGitStatus failed: %v", msg.err)
+	}
+	if !msg.changed[path("docs/branch.md")] || msg.statuses[path("docs/branch.md")] != gitModified {
+		t.Error("Expected docs/branch.md changed since main")
+	}
+	if msg.statuses[path("docs/new.md")] != gitAdded || msg.changed[path("README.md")] {
+		t.Errorf("Expected docs/new.md added and README.md unchanged, got %v", msg.statuses)
+	}
+
+	if msg := loadGitStatus(0, []string{tempDir}, "no-such-branch")().(gitStatusMsg); msg.err == nil {
+		t.Error("Expected an error for an unknown base branch")
+	}
+	if msg := loadGitStatus(0, []string{t.TempDir()}, "")().(gitStatusMsg); msg.err == nil {
+		t.Error("Expected an error outside a work tree")
+	}
+}
+
+func TestLoadGitStatusSeveralRepositories(t *testing.T) {
+	first, second := writeGitFixture(t), writeGitFixture(t)
+	outside := t.TempDir()
+
+	// The paths' common directory is in neither repository
+	m, err := NewDualPaneModelWithRoots([]string{filepath.Join(first, "docs"), filepath.Join(second, "docs"), outside}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	msg := m.loadGitStatus()().(gitStatusMsg)
+	if msg.err != nil {
+		t.Fatalf("loadGitStatus failed: %v", msg.err)
+	}
+	for _, dir := range []string{first, second} {
+		if msg.statuses[filepath.Join(dir, "docs", "changed.md")] != gitModified {
+			t.Errorf("Expected the status of the repository in %s, got %v", dir, msg.statuses)
+		}
+	}
+	if len(msg.changed) != 8 {
+		t.Errorf("Expected the 4 changed files of each repository, got %v", msg.changed)
+	}
+}
+
+func TestDualPaneChangedFiles(t *testing.T) {
+	tempDir := writeGitFixture(t)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+	m.gitBase = "main"
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+	m.Update(m.loadGitStatus()())
+
+	// A read that finishes after a newer one is dropped
+	m.Update(gitStatusMsg{id: m.gitStatusID - 1})
+	if len(m.gitStatuses) == 0 {
+		t.Error("Expected the status of an older read to be ignored")
+	}
+
+	marked := 0
+	for _, row := range m.treeRows {
+		if row.marker != "" {
+			marked++
+		}
+	}
+	if marked != 6 {
+		t.Errorf("Expected 6 files with status markers, got %q", rowTexts(m.treeRows))
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if !m.changedOnly || m.visibleFileCount() != 6 {
+		t.Errorf("Expected only the 6 changed files, got %q", rowTexts(m.treeRows))
+	}
+	if node := m.cursorNode(); node == nil || node.Path != m.loadedPath || !m.gitChanged[m.loadedPath] {
+		t.Errorf("Expected a changed file selected, got %s", m.loadedPath)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if m.changedOnly || m.visibleFileCount() != 8 {
+		t.Errorf("Expected all files again, got %q", rowTexts(m.treeRows))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pathMatcher returns a function reporting whether a slash separated
+// relative path matches query, ignoring case. A query with glob characters
+// is a pattern; anything else is matched as a substring.
+//
+// In patterns, * and ? don't match across directories while ** does. The
+// pattern may match the end of the path at any depth, so */api/*.md matches
+// both services/api/x.md and a/b/api/y.md; a leading / anchors it to the
+// root instead.
+func pathMatcher(query string) func(path string) bool {
+	query = strings.ToLower(query)
+	if !strings.ContainsAny(query, "*?[") {
+		return func(path string) bool {
+			return strings.Contains(strings.ToLower(path), query)
+		}
+	}
+
+	pattern, err := regexp.Compile(globToRegexp(query))
+	if err != nil {
+		return func(string) bool { return false }
+	}
+	return func(path string) bool {
+		return pattern.MatchString(strings.ToLower(path))
+	}
+}
+
+// globToRegexp translates a glob pattern to an equivalent regular
+// expression, as described for pathMatcher.
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	if strings.HasPrefix(glob, "/") {
+		re.WriteString("^")
+		glob = glob[1:]
+	} else {
+		re.WriteString("(?:^|/)")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// **/ matches any number of directories, including none
+					i++
+					re.WriteString("(?:.*/)?")
+				} else {
+					re.WriteString(".*")
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+	return re.String()
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestPathMatcher(t *testing.T) {
+	tests := []struct {
+		query   string
+		path    string
+		matches bool
+	}{
+		{"api", "services/api/users.md", true},
+		{"API", "services/api/users.md", true},
+		{"api", "docs/guide.md", false},
+		{"*/api/*.md", "services/api/users.md", true},
+		{"*/api/*.md", "a/b/api/users.md", true},
+		{"*/api/*.md", "api/users.md", false},
+		{"*/api/*.md", "services/api/v1/users.md", false},
+		{"**/api/**", "services/api/v1/users.md", true},
+		{"/docs/*.md", "docs/guide.md", true},
+		{"/docs/*.md", "old/docs/guide.md", false},
+		{"*.md", "deep/down/notes.md", true},
+		{"adr-00?.md", "adr/adr-001.md", true},
+		{"adr-00[!1].md", "adr/adr-001.md", false},
+		{"adr-00[12].md", "adr/adr-002.md", true},
+		{"[unclosed", "[unclosed", true},
+		{"a+b*.md", "a+b notes.md", true},
+	}
+
+	for _, test := range tests {
+		if got := pathMatcher(test.query)(test.path); got != test.matches {
+			t.Errorf("pathMatcher(%q)(%q) = %t, expected %t", test.query, test.path, got, test.matches)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/denormal/go-gitignore"
+)
+
+// ignoreMatcher applies git's ignore rules to paths of a work tree: the
+// .gitignore of every directory, then .git/info/exclude, then the global
+// core.excludesFile, in that order of precedence.
+type ignoreMatcher struct {
+	base   string              // Work tree root all patterns are relative to
+	repo   gitignore.GitIgnore // .gitignore files and .git/info/exclude
+	global gitignore.GitIgnore // core.excludesFile
+}
+
+// newIgnoreMatcher returns the ignore rules that apply to files under root.
+// Outside a git repository the .gitignore files below root still apply.
+func newIgnoreMatcher(root string) *ignoreMatcher {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return &ignoreMatcher{}
+	}
+
+	base := findWorkTree(root)
+	if base == "" {
+		base = root
+	}
+
+	m := &ignoreMatcher{base: base}
+
+	// The repository caches each parsed .gitignore, which matters because
+	// every path is checked against all the files above it
+	m.repo = gitignore.NewRepositoryWithCache(base, "", gitignore.NewCache(), nil)
+
+	if path := globalExcludesFile(base); path != "" {
+		if file, err := os.Open(path); err == nil {
+			m.global = gitignore.New(file, base, nil)
+			file.Close()
+		}
+	}
+
+	return m
+}
+
+// ignored reports whether path is excluded. Paths outside the work tree are
+// never ignored.
+func (m *ignoreMatcher) ignored(path string, isDir bool) bool {
+	if m == nil || m.base == "" {
+		return false
+	}
+
+	rel, ok := relativeTo(m.base, path)
+	if !ok {
+		return false
+	}
+
+	// The first set of rules with an opinion decides, so a negation in a
+	// .gitignore can re-include a file excluded globally
+	if m.repo != nil {
+		if match := m.repo.Relative(rel, isDir); match != nil {
+			return match.Ignore()
+		}
+	}
+	if m.global != nil {
+		if match := m.global.Relative(rel, isDir); match != nil {
+			return match.Ignore()
+		}
+	}
+	return false
+}
+
+// mdignoreFile is md's own ignore file. It uses gitignore syntax, can be
+// placed in any directory like .gitignore, and applies even with -i, so
+// documents that are checked into git can still be hidden.
+const mdignoreFile = ".mdignore"
+
+// patternSet matches paths against gitignore style patterns relative to a
+// base directory.
+type patternSet struct {
+	base  string
+	rules gitignore.GitIgnore
+}
+
+// newPatternSet returns the patterns for paths under base, or nil if there
+// are none.
+func newPatternSet(base string, patterns []string) *patternSet {
+	if len(patterns) == 0 {
+		return nil
+	}
+	base, err := filepath.Abs(base)
+	if err != nil {
+		return nil
+	}
+	rules := gitignore.New(strings.NewReader(strings.Join(patterns, "\n")), base, nil)
+	return &patternSet{base: base, rules: rules}
+}
+
+// newMdignore returns the rules of the .mdignore files that apply to files
+// under root, from the top of its work tree down.
+func newMdignore(root string) *patternSet {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	base := findWorkTree(root)
+	if base == "" {
+		base = root
+	}
+	rules := gitignore.NewRepositoryWithCache(base, mdignoreFile, gitignore.NewCache(), nil)
+	if rules == nil {
+		return nil
+	}
+	return &patternSet{base: base, rules: rules}
+}
+
+// matches reports whether path matches the patterns. Paths outside the base
+// directory never match.
+func (p *patternSet) matches(path string, isDir bool) bool {
+	if p == nil || p.rules == nil {
+		return false
+	}
+
+	rel, ok := relativeTo(p.base, path)
+	if !ok {
+		return false
+	}
+
+	match := p.rules.Relative(rel, isDir)
+	return match != nil && match.Ignore()
+}
+
+// relativeTo returns the slash separated path of path below base. ok is
+// false for base itself and for paths outside of it.
+func relativeTo(base, path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// findWorkTree returns the root of the git work tree containing dir, or ""
+// if dir isn't inside one.
+func findWorkTree(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// globalExcludesFile returns the path of git's core.excludesFile. Like git,
+// the repository's config overrides the user's, and the default is
+// $XDG_CONFIG_HOME/git/ignore.
+func globalExcludesFile(workTree string) string {
+	home, _ := os.UserHomeDir()
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" && home != "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	var configs []string
+	if configHome != "" {
+		configs = append(configs, filepath.Join(configHome, "git", "config"))
+	}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	configs = append(configs, filepath.Join(workTree, ".git", "config"))
+
+	path := ""
+	for _, config := range configs {
+		if value, ok := gitConfigValue(config, "core", "excludesfile"); ok {
+			path = value
+		}
+	}
+
+	if path == "" {
+		if configHome == "" {
+			return ""
+		}
+		return filepath.Join(configHome, "git", "ignore")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+// gitConfigValue returns the last value of section.key in a git config file.
+// Only plain [section] headers are understood, which covers core settings.
+func gitConfigValue(configPath, section, key string) (string, bool) {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	var value string
+	found := false
+	current := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if end := strings.Index(line, "]"); end > 0 {
+				current = strings.ToLower(strings.TrimSpace(line[1:end]))
+			}
+			continue
+		}
+		if current != section {
+			continue
+		}
+
+		name, val, _ := strings.Cut(line, "=")
+		if strings.ToLower(strings.TrimSpace(name)) != key {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+			val = val[1 : len(val)-1]
+		} else if i := strings.IndexAny(val, "#;"); i >= 0 {
+			val = strings.TrimSpace(val[:i]) // Trailing comment
+		}
+		value, found = val, true
+	}
+	return value, found
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreMatcherRepository(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if err := os.MkdirAll(filepath.Join(configHome, "git"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "git", "ignore"), []byte("*.draft.md\n"), 0644); err != nil {
+		t.Fatalf("Failed to create global excludes: %v", err)
+	}
+
+	tempDir := writeFiles(t, map[string]string{
+		".git/info/exclude":   "scratch.md\n",
+		".gitignore":          "pkg/*/gen/\n..hidden.md\n",
+		"README.md":           "# Root",
+		"..hidden.md":         "# Dots",
+		"scratch.md":          "# Scratch",
+		"notes.draft.md":      "# Draft",
+		"pkg/api/gen/api.md":  "# Generated",
+		"pkg/api/doc.md":      "# API",
+		"docs/.gitignore":     "*.md\n!keep.md\n",
+		"docs/keep.md":        "# Keep",
+		"docs/drop.md":        "# Drop",
+		"docs/sub/deep.md":    "# Deep",
+		"other/idea.draft.md": "# Draft elsewhere",
+	})
+
+	m := newIgnoreMatcher(tempDir)
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"README.md", false},
+		{"..hidden.md", true},    // Inside the work tree despite its name
+		{"scratch.md", true},     // .git/info/exclude
+		{"notes.draft.md", true}, // Global excludes file
+		{"pkg/api/gen", true},    // Directory-scoped wildcard pattern
+		{"pkg/api/doc.md", false},
+		{"docs/keep.md", false},    // Negation in a nested .gitignore
+		{"docs/drop.md", true},     // Nested .gitignore
+		{"docs/sub/deep.md", true}, // Nested rules apply to subdirectories
+		{"other/idea.draft.md", true},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(tempDir, test.path)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Missing fixture %s: %v", test.path, err)
+		}
+		if got := m.ignored(path, info.IsDir()); got != test.ignored {
+			t.Errorf("ignored(%s) = %t, expected %t", test.path, got, test.ignored)
+		}
+	}
+}
+
+func TestFindMarkdownFilesNestedGitignore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tempDir := writeFiles(t, map[string]string{
+		".git/info/exclude":  "scratch.md\n",
+		".gitignore":         "pkg/*/gen/\n",
+		"README.md":          "# Root",
+		"scratch.md":         "# Scratch",
+		"pkg/api/gen/api.md": "# Generated",
+		"pkg/api/doc.md":     "# API",
+		"docs/.gitignore":    "*.md\n!keep.md\n",
+		"docs/keep.md":       "# Keep",
+		"docs/drop.md":       "# Drop",
+	})
+
+	tree, err := FindMarkdownFiles(tempDir, false)
+	if err != nil {
+		t.Fatalf("FindMarkdownFiles failed: %v", err)
+	}
+	files := CollectFiles(tree)
+
+	expected := []string{
+		filepath.Join(tempDir, "docs", "keep.md"),
+		filepath.Join(tempDir, "pkg", "api", "doc.md"),
+		filepath.Join(tempDir, "README.md"),
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("File %d: expected %s, got %s", i, expected[i], files[i])
+		}
+	}
+
+	// -i includes everything again
+	tree, _ = FindMarkdownFiles(tempDir, true)
+	if files := CollectFiles(tree); len(files) != 6 {
+		t.Errorf("Expected 6 files with ignored ones included, got %v", files)
+	}
+
+	// A scan started in a subdirectory still applies the rules above it
+	quick, _ := FindMarkdownFilesQuick(filepath.Join(tempDir, "docs"), false)
+	if files := CollectFiles(quick); len(files) != 1 || filepath.Base(files[0]) != "keep.md" {
+		t.Errorf("Expected only keep.md in docs, got %v", files)
+	}
+}
+
+func TestGlobalExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	workTree := writeFiles(t, map[string]string{})
+	if got := globalExcludesFile(workTree); got != filepath.Join(home, ".config", "git", "ignore") {
+		t.Errorf("Expected default excludes file, got %s", got)
+	}
+
+	config := "[user]\n\tname = Someone\n[core]\n\texcludesFile = ~/.gitignore_global ; comment\n"
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if got := globalExcludesFile(workTree); got != filepath.Join(home, ".gitignore_global") {
+		t.Errorf("Expected excludes file from ~/.gitconfig, got %s", got)
+	}
+}
+
+func TestFindMarkdownFilesPatterns(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tempDir := writeFiles(t, map[string]string{
+		".git/info/exclude":        "",
+		".mdignore":                "vendor/**\nCHANGELOG-*.md\n",
+		"README.md":                "# Root",
+		"CHANGELOG-1.0.md":         "# Old changes",
+		"vendor/lib/README.md":     "# Vendored",
+		"third_party/x/README.md":  "# Third party",
+		"docs/guide.md":            "# Guide",
+		"docs/api/ref.md":          "# Reference",
+		"docs/generated/.mdignore": "*.md\n",
+		"docs/generated/out.md":    "# Generated",
+	})
+
+	files := func(opts FinderOptions) []string {
+		tree, err := FindMarkdownFilesWithOptions(tempDir, opts, -1)
+		if err != nil {
+			t.Fatalf("FindMarkdownFilesWithOptions failed: %v", err)
+		}
+		var rel []string
+		for _, file := range CollectFiles(tree) {
+			path, _ := filepath.Rel(tempDir, file)
+			rel = append(rel, filepath.ToSlash(path))
+		}
+		return rel
+	}
+	expect := func(name string, got []string, expected ...string) {
+		t.Helper()
+		if strings.Join(got, " ") != strings.Join(expected, " ") {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+
+	// .mdignore files apply at every level, even with -i
+	expect(".mdignore", files(FinderOptions{}),
+		"docs/api/ref.md", "docs/guide.md", "third_party/x/README.md", "README.md")
+	expect(".mdignore with -i", files(FinderOptions{IncludeIgnored: true}),
+		"docs/api/ref.md", "docs/guide.md", "third_party/x/README.md", "README.md")
+
+	expect("exclude", files(FinderOptions{Exclude: []string{"third_party/**", "api/"}}),
+		"docs/guide.md", "README.md")
+	expect("include", files(FinderOptions{Include: []string{"docs/**"}}),
+		"docs/api/ref.md", "docs/guide.md")
+	expect("include and exclude", files(FinderOptions{Include: []string{"*.md"}, Exclude: []string{"README.md"}}),
+		"docs/api/ref.md", "docs/guide.md")
+
+	// The quick scan applies the same rules
+	quick, _ := FindMarkdownFilesQuickWithOptions(tempDir, FinderOptions{Exclude: []string{"docs"}})
+	var names []string
+	for _, child := range quick.Children {
+		names = append(names, child.Name)
+	}
+	expect("quick scan", names, "third_party", "README.md")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Link is a markdown link found in a document.
+type Link struct {
+	Text   string
+	Target string
+	Line   int // 0-based source line
+}
+
+var (
+	inlineLinkTarget  = regexp.MustCompile(`\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+["'(][^)]*)?\s*\)`)
+	referenceLinkDef  = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:\s*(?:<([^>]*)>|(\S+))`)
+	codeSpanPattern   = regexp.MustCompile("`+[^`]*`+")
+	urlSchemePattern  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+	linkDirIndexFiles = []string{"README.md", "readme.md", "index.md"}
+)
+
+// ParseLinks returns the links of a markdown document in order, including
+// reference definitions. Images and links inside code are skipped.
+func ParseLinks(content string) []Link {
+	var links []Link
+
+	var fence codeFence
+	for i, line := range strings.Split(content, "\n") {
+		if fence.update(line) {
+			continue
+		}
+
+		if match := referenceLinkDef.FindStringSubmatch(line); match != nil {
+			links = append(links, Link{Text: match[1], Target: match[2] + match[3], Line: i})
+			continue
+		}
+
+		// Blank out code spans so links inside them don't match
+		line = codeSpanPattern.ReplaceAllStringFunc(line, func(code string) string {
+			return strings.Repeat(" ", len(code))
+		})
+
+		for _, loc := range inlineLinkTarget.FindAllStringSubmatchIndex(line, -1) {
+			if loc[0] > 0 && line[loc[0]-1] == '!' {
+				continue // Image
+			}
+			links = append(links, Link{
+				Text:   plainHeadingText(line[loc[2]:loc[3]]),
+				Target: line[loc[4]:loc[5]],
+				Line:   i,
+			})
+		}
+	}
+
+	return links
+}
+
+// isExternalLink reports whether target points outside the local file system,
+// such as https: or mailto: links.
+func isExternalLink(target string) bool {
+	return urlSchemePattern.MatchString(target) || strings.HasPrefix(target, "//")
+}
+
+// InternalLinks returns the links that can be followed inside the viewer:
+// relative paths and #anchors.
+func InternalLinks(content string) []Link {
+	var internal []Link
+	for _, link := range ParseLinks(content) {
+		if link.Target != "" && !isExternalLink(link.Target) {
+			internal = append(internal, link)
+		}
+	}
+	return internal
+}
+
+// resolveLink resolves a link target relative to the document it appears in.
+// It returns the target file ("" for a link within the same document) and the
+// anchor without its leading '#'. A link to a directory resolves to its
+// README or index file if there is one in fsys.
+func resolveLink(fsys treeFS, fromPath, target string) (string, string) {
+	target, anchor, _ := strings.Cut(target, "#")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if unescaped, err := url.PathUnescape(anchor); err == nil {
+		anchor = unescaped
+	}
+	if target == "" {
+		return "", anchor
+	}
+
+	path := filepath.FromSlash(target)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(fromPath), path)
+	}
+	path = filepath.Clean(path)
+
+	if info, err := fsys.Stat(path); err == nil && info.IsDir() {
+		for _, name := range linkDirIndexFiles {
+			if _, err := fsys.Stat(filepath.Join(path, name)); err == nil {
+				return filepath.Join(path, name), anchor
+			}
+		}
+	}
+
+	return path, anchor
+}
+
+// anchorLine returns the display line of the heading with the given anchor.
+func anchorLine(content string, lines []string, raw bool, anchor string) (int, bool) {
+	headings := ParseHeadings(content)
+	displayLines := headingDisplayLines(headings, lines, raw, strings.Count(content, "\n")+1)
+	anchor = strings.ToLower(anchor)
+	for i, heading := range headings {
+		if heading.Anchor == anchor {
+			return displayLines[i], true
+		}
+	}
+	return 0, false
+}
+
+// linkItems formats links for the link popup.
+func linkItems(links []Link) []string {
+	items := make([]string, len(links))
+	for i, link := range links {
+		if link.Text == "" || link.Text == link.Target {
+			items[i] = link.Target
+		} else {
+			items[i] = link.Text + " " + glyphs.arrow + " " + link.Target
+		}
+	}
+	return items
+}
+
+// historyEntry is a position the user can navigate back or forward to.
+type historyEntry struct {
+	path     string
+	viewport int
+}
+
+// navHistory is a browser-style back/forward history.
+type navHistory struct {
+	backStack    []historyEntry
+	forwardStack []historyEntry
+}
+
+// visit records current before navigating somewhere new, which discards the
+// forward history.
+func (h *navHistory) visit(current historyEntry) {
+	h.backStack = append(h.backStack, current)
+	h.forwardStack = nil
+}
+
+// back returns the previous position, remembering current for forward.
+func (h *navHistory) back(current historyEntry) (historyEntry, bool) {
+	if len(h.backStack) == 0 {
+		return historyEntry{}, false
+	}
+	entry := h.backStack[len(h.backStack)-1]
+	h.backStack = h.backStack[:len(h.backStack)-1]
+	h.forwardStack = append(h.forwardStack, current)
+	return entry, true
+}
+
+// forward returns the position undone by back, remembering current for back.
+func (h *navHistory) forward(current historyEntry) (historyEntry, bool) {
+	if len(h.forwardStack) == 0 {
+		return historyEntry{}, false
+	}
+	entry := h.forwardStack[len(h.forwardStack)-1]
+	h.forwardStack = h.forwardStack[:len(h.forwardStack)-1]
+	h.backStack = append(h.backStack, current)
+	return entry, true
+}
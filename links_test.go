@@ -0,0 +1,202 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseLinks(t *testing.T) {
+	content := "# Doc\n" +
+		"See [auth](../design/auth.md#tokens) and [site](https://example.com).\n" +
+		"![diagram](img/arch.png) is an image.\n" +
+		"Use `[not](a-link.md)` literally.\n" +
+		"```\n" +
+		"[also not](code.md)\n" +
+		"```\n" +
+		"Jump to [usage](#usage \"Usage\").\n" +
+		"[ref]: <guide with space.md>\n"
+
+	links := ParseLinks(content)
+	expected := []Link{
+		{Text: "auth", Target: "../design/auth.md#tokens", Line: 1},
+		{Text: "site", Target: "https://example.com", Line: 1},
+		{Text: "usage", Target: "#usage", Line: 7},
+		{Text: "ref", Target: "guide with space.md", Line: 8},
+	}
+
+	if len(links) != len(expected) {
+		t.Fatalf("Expected %d links, got %d: %+v", len(expected), len(links), links)
+	}
+	for i, link := range links {
+		if link != expected[i] {
+			t.Errorf("Link %d: expected %+v, got %+v", i, expected[i], link)
+		}
+	}
+
+	internal := InternalLinks(content)
+	if len(internal) != 3 {
+		t.Errorf("Expected 3 internal links, got %d: %+v", len(internal), internal)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	tempDir := writeFiles(t, map[string]string{"design/README.md": "# Design"})
+
+	from := filepath.Join(tempDir, "docs", "index.md")
+
+	tests := []struct {
+		target, path, anchor string
+	}{
+		{"../design/auth.md#tokens", filepath.Join(tempDir, "design", "auth.md"), "tokens"},
+		{"#usage", "", "usage"},
+		{"my%20notes.md", filepath.Join(tempDir, "docs", "my notes.md"), ""},
+		{"../design/", filepath.Join(tempDir, "design", "README.md"), ""},
+	}
+
+	for _, test := range tests {
+		path, anchor := resolveLink(treeFS{}, from, test.target)
+		if path != test.path || anchor != test.anchor {
+			t.Errorf("resolveLink(%q) = %q, %q; expected %q, %q", test.target, path, anchor, test.path, test.anchor)
+		}
+	}
+}
+
+func TestNavHistory(t *testing.T) {
+	var h navHistory
+
+	h.visit(historyEntry{path: "a.md", viewport: 1})
+	h.visit(historyEntry{path: "b.md", viewport: 2})
+
+	entry, ok := h.back(historyEntry{path: "c.md", viewport: 3})
+	if !ok || entry.path != "b.md" || entry.viewport != 2 {
+		t.Errorf("Expected back to b.md:2, got %+v (%t)", entry, ok)
+	}
+
+	entry, ok = h.forward(historyEntry{path: "b.md", viewport: 2})
+	if !ok || entry.path != "c.md" || entry.viewport != 3 {
+		t.Errorf("Expected forward to c.md:3, got %+v (%t)", entry, ok)
+	}
+
+	// Visiting a new page clears the forward history
+	h.back(historyEntry{path: "c.md"})
+	h.visit(historyEntry{path: "b.md"})
+	if _, ok := h.forward(historyEntry{path: "d.md"}); ok {
+		t.Error("Expected forward history to be cleared by a new visit")
+	}
+}
+
+func writeLinkedDocs(t *testing.T) string {
+	t.Helper()
+
+	return writeFiles(t, map[string]string{
+		"index.md":        "# Index\n\nRead the [auth design](design/auth.md#tokens).\n",
+		"design/auth.md":  "# Auth\n\n" + strings.Repeat("filler\n", 30) + "## Tokens\n\nTokens expire.\n" + strings.Repeat("more\n", 30),
+		"design/other.md": "# Other\n",
+	})
+}
+
+func TestSingleFileFollowLink(t *testing.T) {
+	tempDir := writeLinkedDocs(t)
+	index := filepath.Join(tempDir, "index.md")
+
+	model, _ := NewSingleFileModel(index)
+	model.raw = true
+	model.height = 10
+	model.width = 80
+	model.Update(model.Init()())
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if !model.linkPicker.active || len(model.links) != 1 {
+		t.Fatalf("Expected link picker with 1 link, got %+v", model.links)
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected following the link to load the target")
+	}
+	model.Update(cmd())
+
+	if model.filepath != filepath.Join(tempDir, "design", "auth.md") {
+		t.Errorf("Expected auth.md to be open, got %s", model.filepath)
+	}
+	if model.viewport != 32 {
+		t.Errorf("Expected viewport at the #tokens heading (32), got %d", model.viewport)
+	}
+
+	// Back returns to the index, forward to the anchor position
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model.Update(cmd())
+	if model.filepath != index || model.viewport != 0 {
+		t.Errorf("Expected back to index.md:0, got %s:%d", model.filepath, model.viewport)
+	}
+
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{']'}})
+	model.Update(cmd())
+	if model.filepath != filepath.Join(tempDir, "design", "auth.md") || model.viewport != 32 {
+		t.Errorf("Expected forward to auth.md:32, got %s:%d", model.filepath, model.viewport)
+	}
+}
+
+func TestSingleFileDropsStaleRender(t *testing.T) {
+	tempDir := writeLinkedDocs(t)
+	index := filepath.Join(tempDir, "index.md")
+
+	model, _ := NewSingleFileModel(index)
+	model.raw = true
+	model.height = 10
+	model.width = 80
+	model.Update(model.Init()())
+	indexContent := model.content
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(cmd())
+	lines := strings.Join(model.lines, "\n")
+
+	// A slow render of the index finishes after the link was followed
+	model.Update(contentRenderedMsg{lines: []string{"index"}, source: indexContent, settings: model.renderSettings()})
+	if got := strings.Join(model.lines, "\n"); got != lines {
+		t.Errorf("Expected the render of the previous page to be dropped, got %q", got)
+	}
+}
+
+func TestDualPaneFollowLink(t *testing.T) {
+	tempDir := writeLinkedDocs(t)
+
+	tree, err := FindMarkdownFiles(tempDir, false)
+	if err != nil {
+		t.Fatalf("FindMarkdownFiles failed: %v", err)
+	}
+
+	m := &DualPaneModel{
+		fileTree:   tree,
+		allFiles:   CollectFiles(tree),
+		rootPath:   tempDir,
+		width:      100,
+		height:     12,
+		splitRatio: 0.3,
+		raw:        true,
+	}
+	m.selectFile(m.fileIndex(filepath.Join(tempDir, "index.md")))
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if m.currentFile() != filepath.Join(tempDir, "design", "auth.md") {
+		t.Errorf("Expected auth.md to be selected, got %s", m.currentFile())
+	}
+	if !strings.HasSuffix(m.treeRows[m.treeSelectedIdx].text(), "[-] auth.md") {
+		t.Errorf("Expected tree selection on auth.md, got %q", m.treeRows[m.treeSelectedIdx].text())
+	}
+	if m.contentViewport != 32 {
+		t.Errorf("Expected content scrolled to #tokens (32), got %d", m.contentViewport)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	if m.currentFile() != filepath.Join(tempDir, "index.md") {
+		t.Errorf("Expected back to select index.md, got %s", m.currentFile())
+	}
+}
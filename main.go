@@ -3,44 +3,96 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 var (
-	inclusive bool
+	inclusive  bool
+	hidden     bool
+	extensions string
+	patterns   string
+	includes   patternFlag
+	excludes   patternFlag
+	gitBase    string
+	style      string
+	asciiOnly  bool
 )
 
+// patternFlag collects the patterns of a flag that can be repeated and
+// takes comma separated lists.
+type patternFlag []string
+
+func (p *patternFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternFlag) Set(value string) error {
+	*p = append(*p, splitList(value)...)
+	return nil
+}
+
 func init() {
 	flag.BoolVar(&inclusive, "i", false, "Include files in .gitignore")
+	flag.BoolVar(&hidden, "a", false, "Include hidden files and directories")
+	flag.StringVar(&extensions, "ext", "", "Comma-separated markdown file extensions (default md,markdown,mdown,mkd,mkdn,mdx)")
+	flag.StringVar(&patterns, "glob", "", "Comma-separated file name patterns to treat as markdown, e.g. README,CHANGELOG")
+	flag.Var(&includes, "include", "Only show files matching these gitignore style patterns (repeatable)")
+	flag.Var(&excludes, "exclude", "Hide files and directories matching these gitignore style patterns (repeatable)")
+	flag.StringVar(&style, "style", "", "Rendering style: auto, dark, light, dracula, tokyo-night, notty, ascii, or a glamour JSON stylesheet (default auto)")
+	flag.BoolVar(&asciiOnly, "ascii", false, "Draw with plain ASCII characters, for terminals that can't show box drawing")
+	flag.StringVar(&gitBase, "base", "", "Show only files changed since the current branch forked from this one (c toggles)")
 }
 
 func main() {
 	flag.Parse()
 	args := flag.Args()
 
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error reading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Flags override the config file
+	exts, globs := cfg.Extensions, cfg.Patterns
+	if extensions != "" {
+		exts = splitList(extensions)
+	}
+	if patterns != "" {
+		globs = splitList(patterns)
+	}
+	setMarkdownTypes(exts, globs)
+
+	// Plain characters for terminals that can't show box drawing; the
+	// automatic style follows suit
+	if asciiOnly || !unicodeTerminal() {
+		glyphs = asciiGlyphs
+	}
+	if style == "" {
+		style = cfg.Style
+	}
+	if err := setRenderStyle(style); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	var m tea.Model
-	var err error
 
 	// Check if stdin has data
 	stat, _ := os.Stdin.Stat()
 	hasStdin := (stat.Mode() & os.ModeCharDevice) == 0
 
 	if hasStdin {
-		// Stdin mode - read from pipe
-		content, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			fmt.Printf("Error reading stdin: %v\n", err)
-			os.Exit(1)
-		}
-		m, err = NewSingleFileModelWithContent("stdin", string(content))
+		// Stdin mode - render the pipe as it is read
+		m, err = NewSingleFileModelWithStream("stdin", os.Stdin)
 		if err != nil {
 			fmt.Printf("Error creating stdin viewer: %v\n", err)
 			os.Exit(1)
 		}
-	} else if len(args) > 0 {
+	} else if len(args) == 1 && !isDir(args[0]) && !isArchiveFile(args[0]) {
 		// Single file mode
 		filename := args[0]
 		m, err = NewSingleFileModel(filename)
@@ -49,12 +101,26 @@ func main() {
 			os.Exit(1)
 		}
 	} else {
-		// Directory tree mode
-		m, err = NewDualPaneModel(inclusive)
+		// Tree of the current directory, the given directory or archive, or
+		// several files and directories
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		dual, err := NewDualPaneModelWithRoots(args, FinderOptions{
+			IncludeIgnored: inclusive,
+			IncludeHidden:  hidden,
+			Include:        includes,
+			Exclude:        excludes,
+		})
 		if err != nil {
 			fmt.Printf("Error initializing: %v\n", err)
 			os.Exit(1)
 		}
+
+		// Reviewing a branch starts with the files it changed
+		dual.gitBase = gitBase
+		dual.changedOnly = gitBase != ""
+		m = dual
 	}
 
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
@@ -62,4 +128,14 @@ func main() {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
 	}
+
+	// Reading positions are best effort; failing to save them isn't fatal
+	if err := saveViewerState(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save reading positions: %v\n", err)
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
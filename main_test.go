@@ -6,6 +6,24 @@ import (
 	"testing"
 )
 
+// writeFiles creates a temporary directory holding files, given by their
+// slash separated paths below it, and returns its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", name, err)
+		}
+	}
+	return tempDir
+}
+
 func TestMainFunctionality(t *testing.T) {
 	// Test that the main components can be created without crashing
 
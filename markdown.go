@@ -0,0 +1,166 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultMarkdownExtensions are the extensions recognized when none are
+// configured.
+var defaultMarkdownExtensions = []string{".md", ".markdown", ".mdown", ".mkd", ".mkdn", ".mdx"}
+
+var (
+	// markdownExtensions and markdownPatterns decide which files are shown
+	// in the tree. Set them with setMarkdownTypes.
+	markdownExtensions = defaultMarkdownExtensions
+	markdownPatterns   []string
+
+	mdxImportExport  = regexp.MustCompile(`^(import|export)\s`)
+	mdxComponentTag  = regexp.MustCompile(`^\s*</?[A-Z][\w.]*(\s[^>]*)?/?>\s*$`)
+	mdxComponent     = regexp.MustCompile(`</?[A-Z][\w.]*(\s[^<>]*)?/?>`)
+	mdxComponentOpen = regexp.MustCompile(`^\s*<[A-Z][\w.]*(\s.*)?$`)
+	mdxExpression    = regexp.MustCompile(`^\s*\{.*\}\s*$`)
+)
+
+// setMarkdownTypes configures the files treated as markdown: names ending
+// in one of extensions, which may omit the leading dot, or matching one of
+// the glob patterns. No extensions means the defaults.
+func setMarkdownTypes(extensions, patterns []string) {
+	markdownExtensions = defaultMarkdownExtensions
+	if len(extensions) > 0 {
+		markdownExtensions = nil
+		for _, ext := range extensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext == "" {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			markdownExtensions = append(markdownExtensions, ext)
+		}
+	}
+
+	markdownPatterns = nil
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			markdownPatterns = append(markdownPatterns, pattern)
+		}
+	}
+}
+
+// splitList splits a comma separated flag value.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// isMarkdownFile reports whether a file name is one of the configured
+// markdown types. Extensions are compared case-insensitively.
+func isMarkdownFile(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range markdownExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	for _, pattern := range markdownPatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareForRender adapts a document for the markdown renderer. Front matter
+// is replaced by a header of its main fields, or left out without meta.
+// Mermaid diagrams are drawn as text. MDX files have their imports, exports
+// and JSX stripped, which the renderer would otherwise show as text.
+func prepareForRender(path, content string, meta bool) string {
+	if fm, body, ok := splitFrontMatter(content); ok {
+		content = body
+		if meta {
+			content = metadataHeader(fm) + body
+		}
+	}
+	content = renderMermaidBlocks(content)
+	if strings.EqualFold(filepath.Ext(path), ".mdx") {
+		return stripMDX(content)
+	}
+	return content
+}
+
+// stripMDX removes the MDX-only syntax of a document and keeps its markdown.
+// import and export statements are dropped along with JSX expressions and
+// the tags of components; text between the tags of a component is kept.
+// Fenced code is left alone.
+func stripMDX(content string) string {
+	var out []string
+
+	var fence codeFence
+	depth := 0 // Open brackets of the import or export being skipped
+	statement := false
+	tag := false  // Inside a component tag that spans lines
+	tagDepth := 0 // Open braces of the attributes of that tag
+	for _, line := range strings.Split(content, "\n") {
+		if fence.update(line) {
+			out = append(out, line)
+			continue
+		}
+
+		if tag {
+			tag = !closesTag(line, &tagDepth)
+			continue
+		}
+		if statement || mdxImportExport.MatchString(line) {
+			// Statements can span lines until their brackets are closed
+			depth += strings.Count(line, "{") + strings.Count(line, "(") + strings.Count(line, "[")
+			depth -= strings.Count(line, "}") + strings.Count(line, ")") + strings.Count(line, "]")
+			trimmed := strings.TrimSpace(line)
+			statement = depth > 0 || strings.HasSuffix(trimmed, ",") || strings.HasSuffix(trimmed, "=")
+			if !statement {
+				depth = 0
+			}
+			continue
+		}
+		if mdxComponentTag.MatchString(line) || mdxExpression.MatchString(line) {
+			continue
+		}
+		if mdxComponentOpen.MatchString(line) && !closesTag(line, &tagDepth) {
+			// The attributes continue on the next lines
+			tag = true
+			continue
+		}
+
+		out = append(out, mdxComponent.ReplaceAllString(line, ""))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// closesTag reports whether line holds the > or /> that ends a component
+// tag. A > inside an attribute expression or a quoted value doesn't count;
+// depth carries the open braces of expressions over to the next line.
+func closesTag(line string, depth *int) bool {
+	quote := rune(0)
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '{':
+			*depth++
+		case r == '}':
+			*depth = max(0, *depth-1)
+		case *depth == 0 && (r == '"' || r == '\''):
+			quote = r
+		case *depth == 0 && r == '>':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMarkdownFile(t *testing.T) {
+	t.Cleanup(func() { setMarkdownTypes(nil, nil) })
+
+	setMarkdownTypes(nil, nil)
+	for name, expected := range map[string]bool{
+		"README.md":      true,
+		"notes.MARKDOWN": true,
+		"page.mdx":       true,
+		"post.mkd":       true,
+		"README":         false,
+		"image.png":      false,
+	} {
+		if got := isMarkdownFile(name); got != expected {
+			t.Errorf("isMarkdownFile(%s) = %t with the defaults, expected %t", name, got, expected)
+		}
+	}
+
+	setMarkdownTypes([]string{"txt", ".md", " "}, []string{"README", "CHANGELOG*"})
+	for name, expected := range map[string]bool{
+		"notes.txt":        true,
+		"README.md":        true,
+		"page.mdx":         false,
+		"README":           true,
+		"CHANGELOG.old":    true,
+		"docs-README-copy": false,
+	} {
+		if got := isMarkdownFile(name); got != expected {
+			t.Errorf("isMarkdownFile(%s) = %t with configured types, expected %t", name, got, expected)
+		}
+	}
+}
+
+func TestFindMarkdownFilesConfiguredTypes(t *testing.T) {
+	t.Cleanup(func() { setMarkdownTypes(nil, nil) })
+	tempDir := writeFiles(t, map[string]string{
+		"README":             "# Readme",
+		"guide.markdown":     "# Guide",
+		"docs/page.mdx":      "# Page",
+		"docs/CHANGELOG":     "# Changes",
+		"docs/LICENSE":       "MIT",
+		"docs/notes.md.orig": "# Backup",
+	})
+
+	setMarkdownTypes(nil, []string{"README", "CHANGELOG"})
+	tree, err := FindMarkdownFiles(tempDir, true)
+	if err != nil {
+		t.Fatalf("FindMarkdownFiles failed: %v", err)
+	}
+	if files := CollectFiles(tree); len(files) != 4 {
+		t.Errorf("Expected 4 files, got %v", files)
+	}
+
+	quick, _ := FindMarkdownFilesQuick(tempDir, true)
+	if files := CollectFiles(quick); len(files) != 2 {
+		t.Errorf("Expected README and guide.markdown at the top level, got %v", files)
+	}
+}
+
+func TestStripMDX(t *testing.T) {
+	content := strings.Join([]string{
+		"import { Tabs, Tab } from './tabs'",
+		"import {",
+		"  Callout,",
+		"  Card",
+		"} from '../components'",
+		"export const meta = {",
+		"  title: 'Page',",
+		"}",
+		"",
+		"# Title",
+		"",
+		"<Callout type=\"warning\">",
+		"Mind the **gap**.",
+		"</Callout>",
+		"",
+		"<Tabs",
+		"  items={['a', 'b']}",
+		"  onChange={(tab) => select(tab)}",
+		">",
+		"Tab content.",
+		"</Tabs>",
+		"",
+		"{/* A comment */}",
+		"Inline <Badge text=\"new\" /> component.",
+		"",
+		"```jsx",
+		"import React from 'react'",
+		"<Tabs />",
+		"```",
+		"<div>HTML stays</div>",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"",
+		"# Title",
+		"",
+		"Mind the **gap**.",
+		"",
+		"Tab content.",
+		"",
+		"Inline  component.",
+		"",
+		"```jsx",
+		"import React from 'react'",
+		"<Tabs />",
+		"```",
+		"<div>HTML stays</div>",
+	}, "\n")
+
+	if got := stripMDX(content); got != expected {
+		t.Errorf("Unexpected result:\n%s\n\nexpected:\n%s", got, expected)
+	}
+
+	if got := prepareForRender("README.md", content, true); got != content {
+		t.Error("Expected plain markdown to be left alone")
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// mermaidFencePattern matches the opening fence of a mermaid code block.
+var mermaidFencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*mermaid[ \t]*$")
+
+// renderMermaidBlocks replaces the mermaid code blocks of a document with
+// text drawings of their diagrams. Blocks with diagram types that can't be
+// drawn keep their source.
+func renderMermaidBlocks(content string) string {
+	if !strings.Contains(content, "mermaid") {
+		return content
+	}
+
+	var out, block []string // block holds a mermaid block, fences included
+	var fence codeFence
+	for _, line := range strings.Split(content, "\n") {
+		wasCode := fence.inside()
+		fence.update(line)
+		switch {
+		case !wasCode && fence.inside():
+			if mermaidFencePattern.MatchString(strings.TrimRight(line, "\r")) {
+				block = []string{line}
+				continue
+			}
+		case wasCode && !fence.inside():
+			if block != nil {
+				source := strings.Join(block[1:], "\n")
+				if diagram, ok := renderMermaid(source); ok {
+					out = append(out, "```", diagram, "```")
+				} else {
+					out = append(out, append(block, line)...)
+				}
+				block = nil
+				continue
+			}
+		}
+
+		if block != nil {
+			block = append(block, line)
+		} else {
+			out = append(out, line)
+		}
+	}
+
+	// An unclosed block runs to the end of the document and is left as is
+	out = append(out, block...)
+	return strings.Join(out, "\n")
+}
+
+// renderMermaid draws a mermaid diagram as text. ok is false for diagram
+// types that aren't supported and for sources that can't be parsed.
+func renderMermaid(source string) (string, bool) {
+	lines := mermaidLines(source)
+	if len(lines) == 0 {
+		return "", false
+	}
+
+	// Flowchart statements can follow the header on its line
+	first, rest, _ := strings.Cut(lines[0], ";")
+	header := strings.Fields(first)
+	if len(header) == 0 {
+		return "", false
+	}
+	switch header[0] {
+	case "graph", "flowchart":
+		direction := ""
+		if len(header) > 1 {
+			direction = header[1]
+		}
+		return renderFlowchart(direction, append([]string{rest}, lines[1:]...))
+	case "sequenceDiagram":
+		return renderSequence(lines[1:])
+	}
+	return "", false
+}
+
+// mermaidLines returns the non-empty lines of a diagram without comments,
+// trimmed.
+func mermaidLines(source string) []string {
+	var lines []string
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "%%") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Directions a cell of a canvas connects to.
+const (
+	linkUp uint8 = 1 << iota
+	linkDown
+	linkLeft
+	linkRight
+	linkDotted
+)
+
+// wideFill takes up the cell after a double width character.
+const wideFill rune = -1
+
+// canvas is a grid of characters for drawing diagrams. Lines are recorded
+// as the directions each cell connects to, so crossing and joining lines
+// are drawn with the right box drawing character; text drawn over them
+// wins.
+type canvas struct {
+	text  [][]rune
+	links [][]uint8
+}
+
+func (c *canvas) grow(x, y int) {
+	for len(c.text) <= y {
+		c.text = append(c.text, nil)
+		c.links = append(c.links, nil)
+	}
+	for len(c.text[y]) <= x {
+		c.text[y] = append(c.text[y], 0)
+		c.links[y] = append(c.links[y], 0)
+	}
+}
+
+// put draws a character at x, y.
+func (c *canvas) put(x, y int, r rune) {
+	if x < 0 || y < 0 {
+		return
+	}
+	c.grow(x, y)
+	c.text[y][x] = r
+}
+
+// write draws text starting at x, y.
+func (c *canvas) write(x, y int, text string) {
+	for _, r := range text {
+		c.put(x, y, r)
+		if runewidth.RuneWidth(r) == 2 {
+			x++
+			c.put(x, y, wideFill)
+		}
+		x++
+	}
+}
+
+// free reports whether the cells of text starting at x, y are empty.
+func (c *canvas) free(x, y int, text string) bool {
+	for i := 0; i < runewidth.StringWidth(text); i++ {
+		if c.at(x+i, y) != 0 || c.linksAt(x+i, y) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *canvas) at(x, y int) rune {
+	if y < 0 || y >= len(c.text) || x < 0 || x >= len(c.text[y]) {
+		return 0
+	}
+	return c.text[y][x]
+}
+
+func (c *canvas) linksAt(x, y int) uint8 {
+	if y < 0 || y >= len(c.links) || x < 0 || x >= len(c.links[y]) {
+		return 0
+	}
+	return c.links[y][x]
+}
+
+func (c *canvas) link(x, y int, links uint8) {
+	if x < 0 || y < 0 {
+		return
+	}
+	c.grow(x, y)
+	c.links[y][x] |= links
+}
+
+// hline draws a horizontal line between x1 and x2 inclusive.
+func (c *canvas) hline(x1, x2, y int, dotted bool) {
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	var style uint8
+	if dotted {
+		style = linkDotted
+	}
+	for x := x1; x <= x2; x++ {
+		links := style
+		if x > x1 {
+			links |= linkLeft
+		}
+		if x < x2 {
+			links |= linkRight
+		}
+		if x1 == x2 {
+			links |= linkLeft | linkRight
+		}
+		c.link(x, y, links)
+	}
+}
+
+// vline draws a vertical line between y1 and y2 inclusive.
+func (c *canvas) vline(x, y1, y2 int, dotted bool) {
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	var style uint8
+	if dotted {
+		style = linkDotted
+	}
+	for y := y1; y <= y2; y++ {
+		links := style
+		if y > y1 {
+			links |= linkUp
+		}
+		if y < y2 {
+			links |= linkDown
+		}
+		if y1 == y2 {
+			links |= linkUp | linkDown
+		}
+		c.link(x, y, links)
+	}
+}
+
+// box draws a box with text in the middle row. Rounded boxes are used for
+// shapes other than rectangles.
+func (c *canvas) box(x, y, width, height int, text string, rounded bool) {
+	corners := []rune("┌┐└┘")
+	horizontal, vertical := '─', '│'
+	switch {
+	case glyphs.ascii && rounded:
+		corners, horizontal, vertical = []rune("..''"), '-', '|'
+	case glyphs.ascii:
+		corners, horizontal, vertical = []rune("++++"), '-', '|'
+	case rounded:
+		corners = []rune("╭╮╰╯")
+	}
+
+	for i := 1; i < width-1; i++ {
+		c.put(x+i, y, horizontal)
+		c.put(x+i, y+height-1, horizontal)
+	}
+	for i := 1; i < height-1; i++ {
+		c.put(x, y+i, vertical)
+		c.put(x+width-1, y+i, vertical)
+		for j := 1; j < width-1; j++ {
+			c.put(x+j, y+i, ' ')
+		}
+	}
+	c.put(x, y, corners[0])
+	c.put(x+width-1, y, corners[1])
+	c.put(x, y+height-1, corners[2])
+	c.put(x+width-1, y+height-1, corners[3])
+
+	c.write(x+(width-runewidth.StringWidth(text))/2, y+height/2, text)
+}
+
+// String renders the canvas, without trailing spaces.
+func (c *canvas) String() string {
+	lines := make([]string, len(c.text))
+	for y, row := range c.text {
+		var b strings.Builder
+		for x, r := range row {
+			switch {
+			case r == wideFill:
+			case r != 0:
+				b.WriteRune(r)
+			case c.links[y][x] != 0:
+				b.WriteRune(linkGlyph(c.links[y][x]))
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// linkGlyph returns the line drawing character connecting a cell in the
+// given directions.
+func linkGlyph(links uint8) rune {
+	dotted := links&linkDotted != 0
+	links &^= linkDotted
+
+	vertical := links&^(linkUp|linkDown) == 0
+	horizontal := links&^(linkLeft|linkRight) == 0
+	if glyphs.ascii {
+		switch {
+		case vertical && dotted:
+			return ':'
+		case vertical:
+			return '|'
+		case horizontal && dotted:
+			return '.'
+		case horizontal:
+			return '-'
+		}
+		return '+'
+	}
+
+	switch links {
+	case linkUp, linkDown, linkUp | linkDown:
+		if dotted {
+			return '┆'
+		}
+		return '│'
+	case linkLeft, linkRight, linkLeft | linkRight:
+		if dotted {
+			return '┄'
+		}
+		return '─'
+	case linkDown | linkRight:
+		return '┌'
+	case linkDown | linkLeft:
+		return '┐'
+	case linkUp | linkRight:
+		return '└'
+	case linkUp | linkLeft:
+		return '┘'
+	case linkUp | linkDown | linkRight:
+		return '├'
+	case linkUp | linkDown | linkLeft:
+		return '┤'
+	case linkDown | linkLeft | linkRight:
+		return '┬'
+	case linkUp | linkLeft | linkRight:
+		return '┴'
+	}
+	return '┼'
+}
+
+// arrowGlyph returns the arrowhead pointing in a direction: one of linkUp,
+// linkDown, linkLeft or linkRight.
+func arrowGlyph(direction uint8) rune {
+	arrows := []rune("▲▼◀▶")
+	if glyphs.ascii {
+		arrows = []rune("^v<>")
+	}
+	switch direction {
+	case linkUp:
+		return arrows[0]
+	case linkDown:
+		return arrows[1]
+	case linkLeft:
+		return arrows[2]
+	}
+	return arrows[3]
+}
+
+// mermaidText cleans up the text of a node or message: quotes are removed
+// and line breaks become spaces.
+func mermaidText(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	text = mermaidBreakPattern.ReplaceAllString(text, " ")
+	return strings.TrimSpace(text)
+}
+
+var mermaidBreakPattern = regexp.MustCompile(`(?i)\s*<br\s*/?>\s*`)
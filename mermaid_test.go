@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMermaidBlocks(t *testing.T) {
+	content := strings.Join([]string{
+		"# Architecture",
+		"",
+		"```mermaid",
+		"graph TD",
+		"  A[Start] --> B(End)",
+		"```",
+		"",
+		"```mermaid",
+		"pie title Pets",
+		`  "Dogs" : 386`,
+		"```",
+		"",
+		"````markdown",
+		"```mermaid",
+		"graph TD",
+		"  X --> Y",
+		"```",
+		"````",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"# Architecture",
+		"",
+		"```",
+		"┌───────┐",
+		"│ Start │",
+		"└───┬───┘",
+		"    │",
+		"    ▼",
+		" ╭─────╮",
+		" │ End │",
+		" ╰─────╯",
+		"```",
+		"",
+		// Unsupported diagrams and examples inside other code blocks stay
+		"```mermaid",
+		"pie title Pets",
+		`  "Dogs" : 386`,
+		"```",
+		"",
+		"````markdown",
+		"```mermaid",
+		"graph TD",
+		"  X --> Y",
+		"```",
+		"````",
+	}, "\n")
+
+	if got := renderMermaidBlocks(content); got != expected {
+		t.Errorf("Unexpected result:\n%s\n\nexpected:\n%s", got, expected)
+	}
+
+	// An unclosed block is left as it is
+	unclosed := "Text\n\n```mermaid\ngraph TD\n  A --> B\n"
+	if got := renderMermaidBlocks(unclosed); got != unclosed {
+		t.Errorf("Expected an unclosed block to be left alone, got:\n%s", got)
+	}
+
+	if got := prepareForRender("README.md", content, true); got != expected {
+		t.Errorf("Expected diagrams to be drawn before rendering, got:\n%s", got)
+	}
+}
+
+func TestRenderMermaidUnsupported(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"%% only a comment",
+		"classDiagram\n  Animal <|-- Duck",
+		"gantt\n  title A plan",
+		"graph TD\n  A --> B\n  this is not a statement!",
+		"sequenceDiagram\n  what is this",
+	} {
+		if _, ok := renderMermaid(source); ok {
+			t.Errorf("Expected %q not to be drawn", source)
+		}
+	}
+}
+
+func TestCanvasLines(t *testing.T) {
+	defer func(g glyphSet) { glyphs = g }(glyphs)
+
+	c := &canvas{}
+	c.hline(0, 4, 1, false)
+	c.vline(2, 0, 2, false)
+	c.vline(4, 1, 2, false)
+	c.hline(0, 2, 3, true)
+	c.write(0, 4, "日本")
+
+	expected := "  │\n──┼─┐\n  │ │\n┄┄┄\n日本"
+	if got := c.String(); got != expected {
+		t.Errorf("Unexpected drawing:\n%s\n\nexpected:\n%s", got, expected)
+	}
+
+	glyphs = asciiGlyphs
+	expected = "  |\n--+-+\n  | |\n...\n日本"
+	if got := c.String(); got != expected {
+		t.Errorf("Unexpected ASCII drawing:\n%s\n\nexpected:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Heading is an ATX (# Title) or setext (Title / =====) heading of a document.
+type Heading struct {
+	Level  int
+	Text   string // Heading text with inline markup removed
+	Line   int    // 0-based line of the heading in the source
+	Anchor string // GitHub-style anchor, unique within the document
+}
+
+var (
+	atxHeadingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextH1Pattern    = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	setextH2Pattern    = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	fencePattern       = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+	listItemPattern    = regexp.MustCompile(`^ {0,3}([-*+]|\d+[.)])([ \t]|$)`)
+	inlineLinkPattern  = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	inlineMarkupChars  = "*_`~"
+	anchorDropPattern  = regexp.MustCompile(`[^\p{L}\p{N}\- _]`)
+	anchorSpacePattern = regexp.MustCompile(`[ ]`)
+)
+
+// codeFence follows the fenced code blocks of a document read line by line.
+type codeFence struct {
+	marker string // Opening fence of the current block, "" outside of code
+}
+
+// update moves past line and reports whether it is part of a fenced code
+// block, fences included. As in CommonMark, a block is only closed by a bare
+// fence of the same character that is at least as long as the opening one.
+func (f *codeFence) update(line string) bool {
+	match := fencePattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if f.marker == "" {
+		// The info string of a backtick fence can't hold backticks
+		if match == nil || match[1][0] == '`' && strings.Contains(match[2], "`") {
+			return false
+		}
+		f.marker = match[1]
+		return true
+	}
+
+	if match != nil && match[1][0] == f.marker[0] && len(match[1]) >= len(f.marker) && strings.TrimSpace(match[2]) == "" {
+		f.marker = ""
+	}
+	return true
+}
+
+// inside reports whether the last line was part of a fenced code block that
+// is still open.
+func (f *codeFence) inside() bool {
+	return f.marker != ""
+}
+
+// ParseHeadings returns the headings of a markdown document in order.
+// Headings inside front matter and fenced code blocks are ignored.
+func ParseHeadings(content string) []Heading {
+	var headings []Heading
+	lines := strings.Split(content, "\n")
+	anchors := make(map[string]int)
+
+	add := func(level int, text string, line int) {
+		text = plainHeadingText(text)
+		headings = append(headings, Heading{
+			Level:  level,
+			Text:   text,
+			Line:   line,
+			Anchor: uniqueAnchor(anchors, headingAnchor(text)),
+		})
+	}
+
+	start := 0
+	if fm, _, ok := splitFrontMatter(content); ok {
+		start = fm.lines
+	}
+
+	var fence codeFence
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+
+		// Skip fenced code blocks
+		if fence.update(line) {
+			continue
+		}
+
+		if match := atxHeadingPattern.FindStringSubmatch(line); match != nil {
+			add(len(match[1]), match[2], i)
+			continue
+		}
+
+		// A setext underline turns the preceding paragraph line into a heading
+		if i+1 < len(lines) && isSetextCandidate(line) {
+			next := strings.TrimRight(lines[i+1], "\r")
+			if setextH1Pattern.MatchString(next) {
+				add(1, strings.TrimSpace(line), i)
+				i++
+			} else if setextH2Pattern.MatchString(next) {
+				add(2, strings.TrimSpace(line), i)
+				i++
+			}
+		}
+	}
+
+	return headings
+}
+
+// isSetextCandidate reports whether line can be the text of a setext heading.
+func isSetextCandidate(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return false
+	}
+	if strings.HasPrefix(trimmed, ">") || strings.HasPrefix(trimmed, "|") {
+		return false
+	}
+	return !listItemPattern.MatchString(line) && !setextH2Pattern.MatchString(line) && !setextH1Pattern.MatchString(line)
+}
+
+// plainHeadingText strips links and emphasis markers from heading text.
+func plainHeadingText(text string) string {
+	text = inlineLinkPattern.ReplaceAllString(text, "$1")
+	text = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(inlineMarkupChars, r) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(text)
+}
+
+// headingAnchor returns the anchor GitHub generates for a heading: lower
+// case, punctuation removed and spaces replaced by hyphens.
+func headingAnchor(text string) string {
+	anchor := strings.ToLower(strings.TrimSpace(text))
+	anchor = anchorDropPattern.ReplaceAllString(anchor, "")
+	return anchorSpacePattern.ReplaceAllString(anchor, "-")
+}
+
+// uniqueAnchor disambiguates repeated anchors with -1, -2, ... suffixes.
+func uniqueAnchor(seen map[string]int, anchor string) string {
+	count := seen[anchor]
+	seen[anchor] = count + 1
+	if count == 0 {
+		return anchor
+	}
+	return fmt.Sprintf("%s-%d", anchor, count)
+}
+
+// headingDisplayLines maps each heading to the line it appears on in lines.
+// Raw lines correspond to the source one to one; in rendered output the
+// headings are located in order by their text, falling back to an estimate
+// for headings the renderer changed beyond recognition.
+func headingDisplayLines(headings []Heading, lines []string, raw bool, sourceLines int) []int {
+	result := make([]int, len(headings))
+	next := 0
+	for i, heading := range headings {
+		if raw {
+			result[i] = min(heading.Line, max(0, len(lines)-1))
+			continue
+		}
+
+		result[i] = -1
+		for j := next; j < len(lines); j++ {
+			if renderedLineHasHeading(lines[j], heading.Text) {
+				result[i] = j
+				next = j + 1
+				break
+			}
+		}
+		if result[i] < 0 {
+			result[i] = max(next, heading.Line*len(lines)/max(1, sourceLines))
+			result[i] = min(result[i], max(0, len(lines)-1))
+		}
+	}
+	return result
+}
+
+func renderedLineHasHeading(line, text string) bool {
+	plain := strings.TrimFunc(stripANSI(line), unicode.IsSpace)
+	if plain == "" || text == "" {
+		return false
+	}
+	plain = strings.TrimSpace(strings.TrimLeft(plain, "#"))
+	return plain == text || strings.HasPrefix(plain, text)
+}
+
+// outlineItems formats headings for the outline popup, indented by level.
+func outlineItems(headings []Heading) []string {
+	minLevel := 6
+	for _, heading := range headings {
+		minLevel = min(minLevel, heading.Level)
+	}
+
+	items := make([]string, len(headings))
+	for i, heading := range headings {
+		items[i] = strings.Repeat("  ", heading.Level-minLevel) + heading.Text
+	}
+	return items
+}
+
+// currentHeading returns the index of the last heading at or above viewport.
+func currentHeading(lines []int, viewport int) int {
+	current := 0
+	for i, line := range lines {
+		if line <= viewport {
+			current = i
+		}
+	}
+	return current
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseHeadings(t *testing.T) {
+	content := "# Title\n" +
+		"\n" +
+		"Intro text.\n" +
+		"\n" +
+		"Setext One\n" +
+		"==========\n" +
+		"\n" +
+		"## Install **now** ##\n" +
+		"\n" +
+		"```bash\n" +
+		"# not a heading\n" +
+		"```\n" +
+		"\n" +
+		"Setext Two\n" +
+		"----------\n" +
+		"\n" +
+		"- list item\n" +
+		"---\n" +
+		"\n" +
+		"### [Linked](other.md) heading\n" +
+		"## Install now\n" +
+		"#hashtag is not a heading\n"
+
+	headings := ParseHeadings(content)
+
+	expected := []Heading{
+		{Level: 1, Text: "Title", Line: 0, Anchor: "title"},
+		{Level: 1, Text: "Setext One", Line: 4, Anchor: "setext-one"},
+		{Level: 2, Text: "Install now", Line: 7, Anchor: "install-now"},
+		{Level: 2, Text: "Setext Two", Line: 13, Anchor: "setext-two"},
+		{Level: 3, Text: "Linked heading", Line: 19, Anchor: "linked-heading"},
+		{Level: 2, Text: "Install now", Line: 20, Anchor: "install-now-1"},
+	}
+
+	if len(headings) != len(expected) {
+		t.Fatalf("Expected %d headings, got %d: %+v", len(expected), len(headings), headings)
+	}
+	for i, heading := range headings {
+		if heading != expected[i] {
+			t.Errorf("Heading %d: expected %+v, got %+v", i, expected[i], heading)
+		}
+	}
+}
+
+func TestCodeFence(t *testing.T) {
+	lines := []string{
+		"```md", // Opens
+		"```js", // A fence with an info string doesn't close
+		"# inside",
+		"~~~", // Nor does one of another character
+		"```", // Closes
+		"# outside",
+		"````",     // Opens
+		"```",      // Too short to close
+		"````  ",   // Closes
+		"``` `a` ", // Backticks in the info string: not a fence
+	}
+	expected := []bool{true, true, true, true, true, false, true, true, true, false}
+
+	var fence codeFence
+	for i, line := range lines {
+		if got := fence.update(line); got != expected[i] {
+			t.Errorf("Line %d %q: expected code %t, got %t", i, line, expected[i], got)
+		}
+	}
+
+	headings := ParseHeadings("```\n```js\n# Not a heading\n```\n# Heading\n")
+	if len(headings) != 1 || headings[0].Line != 4 {
+		t.Errorf("Expected only the heading after the block, got %+v", headings)
+	}
+}
+
+func TestHeadingAnchor(t *testing.T) {
+	tests := map[string]string{
+		"Getting Started":       "getting-started",
+		"API v2.0 (beta)!":      "api-v20-beta",
+		"snake_case & kebab-ok": "snake_case--kebab-ok",
+		"Ünïcode Täxt":          "ünïcode-täxt",
+	}
+
+	for text, expected := range tests {
+		if anchor := headingAnchor(text); anchor != expected {
+			t.Errorf("headingAnchor(%q) = %q, expected %q", text, anchor, expected)
+		}
+	}
+}
+
+func TestHeadingDisplayLines(t *testing.T) {
+	headings := []Heading{
+		{Level: 1, Text: "Title", Line: 0},
+		{Level: 2, Text: "Usage", Line: 4},
+		{Level: 2, Text: "Title", Line: 8},
+	}
+
+	// Rendered output: glamour prefixes and styles headings
+	rendered := []string{
+		"",
+		"\x1b[1m  Title  \x1b[0m",
+		"",
+		"  Some text mentioning the Title.",
+		"",
+		"\x1b[1m  ## Usage\x1b[0m",
+		"",
+		"  more",
+		"  ## Title",
+	}
+
+	lines := headingDisplayLines(headings, rendered, false, 10)
+	expected := []int{1, 5, 8}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Heading %d: expected line %d, got %d", i, expected[i], lines[i])
+		}
+	}
+
+	// Raw lines map directly
+	lines = headingDisplayLines(headings, make([]string, 10), true, 10)
+	if lines[1] != 4 || lines[2] != 8 {
+		t.Errorf("Expected raw lines [0 4 8], got %v", lines)
+	}
+}
+
+func TestSingleFileOutlineJump(t *testing.T) {
+	content := "# Top\n\n" + strings.Repeat("text\n", 40) + "## Middle\n\n" + strings.Repeat("more\n", 40)
+
+	model := &SingleFileModel{
+		content: content,
+		raw:     true,
+		height:  10,
+		width:   80,
+	}
+	model.Update(contentRenderedMsg{lines: strings.Split(content, "\n"), source: content, settings: model.renderSettings()})
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+	if !model.outline.active {
+		t.Fatal("Expected o to open the outline")
+	}
+	if len(model.outline.items) != 2 {
+		t.Fatalf("Expected 2 outline items, got %v", model.outline.items)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if model.outline.active {
+		t.Error("Expected outline to close after choosing a heading")
+	}
+	if model.viewport != 42 {
+		t.Errorf("Expected viewport at the Middle heading (42), got %d", model.viewport)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// placeOverlay draws fg on top of bg with its top-left corner at column x,
+// row y. Both may contain ANSI styling; the parts of bg to the left and right
+// of the overlay are kept.
+func placeOverlay(x, y int, fg, bg string) string {
+	bgLines := strings.Split(bg, "\n")
+	fgLines := strings.Split(fg, "\n")
+
+	for i, fgLine := range fgLines {
+		row := y + i
+		if row < 0 || row >= len(bgLines) {
+			continue
+		}
+
+		bgLine := bgLines[row]
+		left := ansi.Truncate(bgLine, x, "")
+		if pad := x - ansi.StringWidth(left); pad > 0 {
+			left += strings.Repeat(" ", pad)
+		}
+		right := ansi.TruncateLeft(bgLine, x+ansi.StringWidth(fgLine), "")
+
+		bgLines[row] = left + "\x1b[0m" + fgLine + "\x1b[0m" + right
+	}
+
+	return strings.Join(bgLines, "\n")
+}
+
+// centerOverlay draws fg in the middle of a width x height bg.
+func centerOverlay(fg, bg string, width, height int) string {
+	fgWidth := 0
+	fgLines := strings.Split(fg, "\n")
+	for _, line := range fgLines {
+		fgWidth = max(fgWidth, ansi.StringWidth(line))
+	}
+
+	x := max(0, (width-fgWidth)/2)
+	y := max(0, (height-len(fgLines))/2)
+	return placeOverlay(x, y, fg, bg)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// picker is a popup list the user picks a single entry from, drawn over the
+// current view.
+type picker struct {
+	active bool
+	title  string
+	items  []string
+	cursor int
+	offset int
+}
+
+// open shows the popup with items, placing the cursor on selected.
+func (p *picker) open(title string, items []string, selected int) {
+	p.active = true
+	p.title = title
+	p.items = items
+	p.cursor = max(0, min(selected, len(items)-1))
+	p.offset = 0
+}
+
+// handleKey applies a key press to the popup. It returns the index of the
+// chosen item, or -1 if nothing was chosen.
+func (p *picker) handleKey(msg tea.KeyMsg, visible int) int {
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		p.active = false
+	case "enter", "l", "right":
+		p.active = false
+		if p.cursor < len(p.items) {
+			return p.cursor
+		}
+	case "j", "down", "ctrl+n":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	case "k", "up", "ctrl+p":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "g", "home":
+		p.cursor = 0
+	case "G", "end":
+		p.cursor = max(0, len(p.items)-1)
+	}
+
+	p.scrollToCursor(visible)
+	return -1
+}
+
+func (p *picker) scrollToCursor(visible int) {
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if visible > 0 && p.cursor >= p.offset+visible {
+		p.offset = p.cursor - visible + 1
+	}
+}
+
+// visibleRows returns how many items fit in a popup of the given height,
+// which also holds the border and title lines.
+func (p *picker) visibleRows(height int) int {
+	return max(1, height-3)
+}
+
+// view renders the popup, at most width x height cells including the border.
+func (p *picker) view(width, height int) string {
+	innerWidth := max(10, width-4)
+	visible := p.visibleRows(height)
+	p.scrollToCursor(visible)
+
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230"))
+
+	lines := []string{titleStyle.Render(truncateToWidth(p.title, innerWidth))}
+	if len(p.items) == 0 {
+		lines = append(lines, "  (none)")
+	}
+	for i := p.offset; i < len(p.items) && i < p.offset+visible; i++ {
+		if i == p.cursor {
+			lines = append(lines, selectedStyle.Render(truncateToWidth(glyphs.cursor+" "+p.items[i], innerWidth)))
+		} else {
+			lines = append(lines, truncateToWidth("  "+p.items[i], innerWidth))
+		}
+	}
+
+	return lipgloss.NewStyle().
+		BorderStyle(glyphs.focusedBorder).
+		BorderForeground(lipgloss.Color("62")).
+		Width(innerWidth).
+		Render(strings.Join(lines, "\n"))
+}
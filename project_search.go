@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// maxProjectSearchResults caps the result list so a very common pattern
+// doesn't flood the UI.
+const maxProjectSearchResults = 1000
+
+// ProjectSearchResult is a single match found by SearchFiles.
+type ProjectSearchResult struct {
+	Path       string // File containing the match
+	Line       int    // 1-based line number of the match
+	Match      string // Text that matched
+	Snippet    string // The matching line, trimmed, for display
+	Occurrence int    // How many times Match occurred earlier in the file
+}
+
+// SearchFiles greps files in fsys for pattern, either as a literal string or
+// as a regular expression. Literal patterns without upper case letters
+// ignore case.
+func SearchFiles(fsys treeFS, files []string, pattern string, useRegex bool) ([]ProjectSearchResult, error) {
+	re, err := compileSearchPattern(pattern, useRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ProjectSearchResult
+	for _, path := range files {
+		content, err := fsys.ReadFile(path)
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			// Unreadable or binary files are skipped
+			continue
+		}
+
+		seen := make(map[string]int) // Matches so far per matched text
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			line := scanner.Text()
+			for i, loc := range re.FindAllStringIndex(line, -1) {
+				if loc[0] == loc[1] {
+					continue // Ignore empty matches such as "^"
+				}
+				match := line[loc[0]:loc[1]]
+				key := match
+				if smartCase(match) {
+					key = strings.ToLower(match)
+				}
+				if i == 0 {
+					results = append(results, ProjectSearchResult{
+						Path:       path,
+						Line:       lineNum,
+						Match:      match,
+						Snippet:    strings.TrimSpace(line),
+						Occurrence: seen[key],
+					})
+					if len(results) >= maxProjectSearchResults {
+						return results, nil
+					}
+				}
+				seen[key]++
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func compileSearchPattern(pattern string, useRegex bool) (*regexp.Regexp, error) {
+	if !useRegex {
+		pattern = regexp.QuoteMeta(pattern)
+		if smartCase(pattern) {
+			pattern = "(?i)" + pattern
+		}
+	}
+	return regexp.Compile(pattern)
+}
+
+// locateOccurrence returns the index of the line holding the n-th (0-based)
+// occurrence of text in lines, ignoring escape sequences.
+func locateOccurrence(lines []string, text string, n int) (int, bool) {
+	needle := []rune(text)
+	fold := smartCase(text)
+	seen := 0
+	for i, line := range lines {
+		plain, _ := splitANSI(line)
+		seen += len(matchRanges(plain, needle, fold))
+		if seen > n {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// projectSearchState holds the project-wide search prompt and its results,
+// which replace the tree pane while shown.
+type projectSearchState struct {
+	active   bool   // Result list is shown in place of the tree
+	typing   bool   // Prompt is open
+	input    string // Pattern being typed or last searched
+	regex    bool   // Treat the pattern as a regular expression
+	running  bool   // Search is in progress
+	id       int    // Incremented for every search, to drop stale results
+	results  []ProjectSearchResult
+	selected int
+	viewport int
+	err      error
+}
+
+// projectSearchDoneMsg delivers the results of search id.
+type projectSearchDoneMsg struct {
+	id      int
+	results []ProjectSearchResult
+	err     error
+}
+
+func searchFilesAsync(id int, fsys treeFS, files []string, pattern string, useRegex bool) tea.Cmd {
+	return func() tea.Msg {
+		results, err := SearchFiles(fsys, files, pattern, useRegex)
+		return projectSearchDoneMsg{id: id, results: results, err: err}
+	}
+}
+
+// updateProjectSearchInput handles a key press while the project search
+// prompt is open.
+func (m *DualPaneModel) updateProjectSearchInput(msg tea.KeyMsg) tea.Cmd {
+	ps := &m.projectSearch
+	switch msg.Type {
+	case tea.KeyEnter:
+		ps.typing = false
+		if ps.input == "" {
+			ps.active = false
+			return nil
+		}
+		ps.running = true
+		ps.err = nil
+		ps.id++
+		m.focusedPane = 0
+		return searchFilesAsync(ps.id, m.fsys, m.allFiles, ps.input, ps.regex)
+	case tea.KeyEsc, tea.KeyCtrlC:
+		ps.typing = false
+		ps.active = ps.results != nil
+		return nil
+	case tea.KeyCtrlR:
+		ps.regex = !ps.regex
+		return nil
+	}
+
+	if input, ok := editPrompt(ps.input, msg); ok {
+		ps.input = input
+	}
+	return nil
+}
+
+// updateProjectSearchResults handles navigation in the result list. It
+// reports false for keys that should fall through to the normal bindings.
+func (m *DualPaneModel) updateProjectSearchResults(msg tea.KeyMsg) bool {
+	ps := &m.projectSearch
+	switch msg.String() {
+	case "j", "down":
+		if ps.selected < len(ps.results)-1 {
+			ps.selected++
+		}
+	case "k", "up":
+		if ps.selected > 0 {
+			ps.selected--
+		}
+	case "g", "home":
+		ps.selected = 0
+	case "G", "end":
+		ps.selected = max(0, len(ps.results)-1)
+	case "enter", "l", "right":
+		if ps.selected < len(ps.results) {
+			m.openProjectSearchResult(ps.results[ps.selected])
+		}
+	case "esc":
+		ps.active = false
+	default:
+		return false
+	}
+
+	m.adjustProjectSearchViewport()
+	return true
+}
+
+func (m *DualPaneModel) adjustProjectSearchViewport() {
+	ps := &m.projectSearch
+	visible := m.height - 2 - 1 // Tree pane height minus the summary line
+	if ps.selected < ps.viewport {
+		ps.viewport = ps.selected
+	} else if visible > 0 && ps.selected >= ps.viewport+visible {
+		ps.viewport = ps.selected - visible + 1
+	}
+}
+
+// openProjectSearchResult loads the file of a result into the content pane
+// and scrolls to the match, which stays highlighted like an in-document
+// search.
+func (m *DualPaneModel) openProjectSearchResult(result ProjectSearchResult) {
+	index := -1
+	for i, file := range m.allFiles {
+		if file == result.Path {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return
+	}
+
+	m.selectFile(index)
+	m.focusedPane = 1
+
+	m.search.clear()
+	m.search.query = result.Match
+	m.search.backward = false
+	m.search.refresh(m.renderedLines)
+
+	// Raw lines map one to one; rendered output is located by counting
+	// occurrences of the matched text
+	line := result.Line - 1
+	if !m.raw {
+		if found, ok := locateOccurrence(m.renderedLines, result.Match, result.Occurrence); ok {
+			line = found
+		} else {
+			// Markup around the match changed its text; fall back to the
+			// proportional position in the document
+			sourceLines := strings.Count(m.currentContent, "\n") + 1
+			line = line * len(m.renderedLines) / max(1, sourceLines)
+		}
+	}
+	m.search.jumpFrom(line, false)
+	m.scrollContentTo(line)
+}
+
+// projectSearchView renders the result list for the tree pane.
+func (m *DualPaneModel) projectSearchView(width, height int) string {
+	ps := &m.projectSearch
+
+	var summary string
+	switch {
+	case ps.running:
+		summary = fmt.Sprintf("Searching for %q...", ps.input)
+	case ps.err != nil:
+		summary = "Error: " + ps.err.Error()
+	case len(ps.results) >= maxProjectSearchResults:
+		summary = fmt.Sprintf("%d+ results for %q", len(ps.results), ps.input)
+	default:
+		summary = fmt.Sprintf("%d results for %q", len(ps.results), ps.input)
+	}
+
+	lines := []string{truncateToWidth(summary, width)}
+
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230"))
+	for i := ps.viewport; i < len(ps.results) && len(lines) < height; i++ {
+		result := ps.results[i]
+		relPath, err := filepath.Rel(m.rootPath, result.Path)
+		if err != nil {
+			relPath = result.Path
+		}
+
+		line := fmt.Sprintf("%s:%d: %s", relPath, result.Line, result.Snippet)
+		if i == ps.selected && m.focusedPane == 0 {
+			line = selectedStyle.Render(truncateToWidth(glyphs.cursor+" "+line, width))
+		} else {
+			line = truncateToWidth("  "+line, width)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// truncateToWidth shortens s to fit in width terminal cells.
+func truncateToWidth(s string, width int) string {
+	if width <= 0 || runewidth.StringWidth(s) <= width {
+		return s
+	}
+	if width > 3 {
+		return runewidth.Truncate(s, width-3, "...")
+	}
+	return runewidth.Truncate(s, width, "")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func writeSearchFixture(t *testing.T) (string, []string) {
+	t.Helper()
+
+	tempDir := writeFiles(t, map[string]string{
+		"a.md":      "# Alpha\n\nDeploy with make deploy.\n",
+		"docs/b.md": "# Beta\n\nNothing to see.\n\nRun `deploy` again, then Deploy.\n",
+		"docs/c.md": "# Gamma\n\nUnrelated.\n",
+	})
+
+	var paths []string
+	for _, name := range []string{"a.md", "docs/b.md", "docs/c.md"} {
+		paths = append(paths, filepath.Join(tempDir, name))
+	}
+	return tempDir, paths
+}
+
+func TestSearchFilesLiteral(t *testing.T) {
+	_, files := writeSearchFixture(t)
+
+	results, err := SearchFiles(treeFS{}, files, "deploy", false)
+	if err != nil {
+		t.Fatalf("SearchFiles failed: %v", err)
+	}
+
+	// One result per matching line, case-insensitive for lower case patterns
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d: %+v", len(results), results)
+	}
+
+	if filepath.Base(results[0].Path) != "a.md" || results[0].Line != 3 {
+		t.Errorf("Expected first result at a.md:3, got %s:%d", results[0].Path, results[0].Line)
+	}
+	if results[0].Match != "Deploy" || results[0].Occurrence != 0 {
+		t.Errorf("Expected first match 'Deploy' (occurrence 0), got %q (%d)", results[0].Match, results[0].Occurrence)
+	}
+	if !strings.Contains(results[1].Snippet, "Run `deploy` again") {
+		t.Errorf("Expected snippet of the matching line, got %q", results[1].Snippet)
+	}
+
+	// Upper case patterns are exact
+	results, err = SearchFiles(treeFS{}, files, "Deploy", false)
+	if err != nil {
+		t.Fatalf("SearchFiles failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results for 'Deploy', got %d", len(results))
+	}
+
+	// Literal mode doesn't interpret regex syntax
+	results, _ = SearchFiles(treeFS{}, files, "de.*y", false)
+	if len(results) != 0 {
+		t.Errorf("Expected no results for literal 'de.*y', got %d", len(results))
+	}
+}
+
+func TestSearchFilesRegex(t *testing.T) {
+	_, files := writeSearchFixture(t)
+
+	results, err := SearchFiles(treeFS{}, files, "^# (Beta|Gamma)$", true)
+	if err != nil {
+		t.Fatalf("SearchFiles failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 regex results, got %d", len(results))
+	}
+
+	if _, err := SearchFiles(treeFS{}, files, "(unclosed", true); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+func TestLocateOccurrence(t *testing.T) {
+	lines := []string{"deploy here", "\x1b[1mdeploy\x1b[0m and deploy", "none"}
+
+	tests := []struct {
+		n        int
+		expected int
+		found    bool
+	}{
+		{0, 0, true},
+		{1, 1, true},
+		{2, 1, true},
+		{3, 0, false},
+	}
+
+	for _, test := range tests {
+		line, ok := locateOccurrence(lines, "deploy", test.n)
+		if ok != test.found || (ok && line != test.expected) {
+			t.Errorf("locateOccurrence(%d) = %d, %t; expected %d, %t", test.n, line, ok, test.expected, test.found)
+		}
+	}
+}
+
+func TestProjectSearchOpensResult(t *testing.T) {
+	tempDir, files := writeSearchFixture(t)
+
+	m := &DualPaneModel{
+		allFiles:   files,
+		rootPath:   tempDir,
+		height:     20,
+		width:      100,
+		splitRatio: 0.3,
+		raw:        true,
+	}
+
+	// Type the pattern and run the search
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyCtrlF},
+		{Type: tea.KeyRunes, Runes: []rune("again")},
+	}
+	for _, key := range keys {
+		m.Update(key)
+	}
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected enter to start the search")
+	}
+	m.Update(cmd())
+
+	if len(m.projectSearch.results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(m.projectSearch.results))
+	}
+	if !strings.Contains(m.projectSearchView(80, 10), "docs/b.md:5:") {
+		t.Errorf("Expected result list to show relative path and line, got %q", m.projectSearchView(80, 10))
+	}
+
+	// Picking the result opens the file scrolled to the match
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if m.selectedIndex != 1 {
+		t.Errorf("Expected docs/b.md to be selected, got index %d", m.selectedIndex)
+	}
+	if m.focusedPane != 1 {
+		t.Error("Expected focus to move to the content pane")
+	}
+	if m.search.query != "again" {
+		t.Errorf("Expected match to be highlighted, got query %q", m.search.query)
+	}
+	if m.search.current < 0 || m.search.matches[m.search.current].line != 4 {
+		t.Errorf("Expected the match on line 5 to be selected, got %v (current %d)", m.search.matches, m.search.current)
+	}
+}
+
+func TestProjectSearchDropsStaleResults(t *testing.T) {
+	tempDir, files := writeSearchFixture(t)
+
+	m := &DualPaneModel{
+		allFiles:   files,
+		rootPath:   tempDir,
+		height:     20,
+		width:      100,
+		splitRatio: 0.3,
+		raw:        true,
+	}
+
+	// Search for the same text literally, then as a regular expression
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("de.loy")})
+	_, literal := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	_, regex := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if literal == nil || regex == nil || !m.projectSearch.regex {
+		t.Fatal("Expected two searches to start")
+	}
+
+	// The literal search finishing last doesn't replace the newer results
+	m.Update(regex())
+	m.Update(literal())
+	if m.projectSearch.running || len(m.projectSearch.results) != 2 {
+		t.Errorf("Expected the 2 regular expression matches, got %d", len(m.projectSearch.results))
+	}
+}
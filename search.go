@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Escape sequences used to highlight search matches. Reverse video is used so
+// the highlight works on top of whatever colors glamour already applied.
+const (
+	highlightOn  = "\x1b[7m"
+	highlightOff = "\x1b[27m"
+)
+
+// searchState holds the less-style in-document search shared by both views.
+type searchState struct {
+	typing   bool          // Prompt is open and receiving keystrokes
+	input    string        // Text typed at the prompt
+	query    string        // Last confirmed query
+	backward bool          // Direction of the last search ('?' searches backward)
+	matches  []searchMatch // Every occurrence of the query, in document order
+	current  int           // Index into matches of the selected match, -1 if none
+	origin   int           // Viewport when the prompt was opened, restored on cancel
+}
+
+// searchMatch is one occurrence of the query: its line and the rune offset
+// in the visible text of that line where it starts.
+type searchMatch struct {
+	line   int
+	column int
+}
+
+type searchInputResult int
+
+const (
+	searchInputIgnored searchInputResult = iota
+	searchInputEdited
+	searchInputConfirmed
+	searchInputCancelled
+)
+
+// begin opens the search prompt. The viewport is remembered so an aborted
+// search can put the user back where they started.
+func (s *searchState) begin(backward bool, viewport int) {
+	s.typing = true
+	s.input = ""
+	s.backward = backward
+	s.origin = viewport
+}
+
+// activeQuery returns the query that should currently be highlighted, which
+// is the prompt text while typing so the search is incremental.
+func (s *searchState) activeQuery() string {
+	if s.typing {
+		return s.input
+	}
+	return s.query
+}
+
+// handleInput applies a key press to the open prompt.
+func (s *searchState) handleInput(msg tea.KeyMsg) searchInputResult {
+	switch msg.Type {
+	case tea.KeyEnter:
+		s.typing = false
+		if s.input != "" {
+			s.query = s.input
+		}
+		return searchInputConfirmed
+	case tea.KeyEsc, tea.KeyCtrlC:
+		s.typing = false
+		s.input = ""
+		return searchInputCancelled
+	}
+
+	input, ok := editPrompt(s.input, msg)
+	if !ok {
+		return searchInputIgnored
+	}
+	s.input = input
+	return searchInputEdited
+}
+
+// clear drops the current query and its highlights.
+func (s *searchState) clear() {
+	s.query = ""
+	s.input = ""
+	s.matches = nil
+	s.current = -1
+}
+
+// refresh recomputes the matches, e.g. after the content was
+// re-rendered or the query changed.
+func (s *searchState) refresh(lines []string) {
+	s.matches = findMatches(lines, s.activeQuery())
+	s.current = -1
+}
+
+// jumpFrom selects the first match at or after line (or at or before it when
+// searching backward), wrapping around the document, and returns its line.
+func (s *searchState) jumpFrom(line int, backward bool) (int, bool) {
+	if len(s.matches) == 0 {
+		s.current = -1
+		return 0, false
+	}
+
+	if backward {
+		s.current = len(s.matches) - 1
+		for i := len(s.matches) - 1; i >= 0; i-- {
+			if s.matches[i].line <= line {
+				s.current = i
+				return s.matches[i].line, true
+			}
+		}
+		return s.matches[s.current].line, true
+	}
+
+	s.current = 0
+	for i, match := range s.matches {
+		if match.line >= line {
+			s.current = i
+			return match.line, true
+		}
+	}
+	return s.matches[0].line, true
+}
+
+// next moves to the following match in the search direction (n), or the
+// opposite direction when reverse is set (N).
+func (s *searchState) next(viewport int, reverse bool) (int, bool) {
+	if len(s.matches) == 0 {
+		return 0, false
+	}
+
+	backward := s.backward != reverse
+	if s.current < 0 {
+		if backward {
+			return s.jumpFrom(viewport-1, true)
+		}
+		return s.jumpFrom(viewport+1, false)
+	}
+
+	if backward {
+		s.current = (s.current - 1 + len(s.matches)) % len(s.matches)
+	} else {
+		s.current = (s.current + 1) % len(s.matches)
+	}
+	return s.matches[s.current].line, true
+}
+
+// highlight returns line with every match of the active query highlighted.
+func (s *searchState) highlight(line string) string {
+	query := s.activeQuery()
+	if query == "" {
+		return line
+	}
+	return highlightMatches(line, query)
+}
+
+// status describes the search for the status line, or returns "" when there
+// is nothing to show.
+func (s *searchState) status() string {
+	prefix := "/"
+	if s.backward {
+		prefix = "?"
+	}
+
+	if s.typing {
+		return prefix + s.input
+	}
+	if s.query == "" {
+		return ""
+	}
+	if len(s.matches) == 0 {
+		return fmt.Sprintf("Pattern not found: %s", s.query)
+	}
+	if s.current < 0 {
+		return fmt.Sprintf("%s%s (%d matches)", prefix, s.query, len(s.matches))
+	}
+	return fmt.Sprintf("%s%s (match %d of %d)", prefix, s.query, s.current+1, len(s.matches))
+}
+
+// editPrompt applies basic line editing (typing, backspace, ctrl+u) to a
+// prompt's text. It reports false for keys it doesn't handle.
+func editPrompt(input string, msg tea.KeyMsg) (string, bool) {
+	switch msg.Type {
+	case tea.KeyRunes:
+		return input + string(msg.Runes), true
+	case tea.KeySpace:
+		return input + " ", true
+	case tea.KeyBackspace:
+		if input == "" {
+			return input, true
+		}
+		runes := []rune(input)
+		return string(runes[:len(runes)-1]), true
+	case tea.KeyCtrlU:
+		return "", true
+	}
+	return input, false
+}
+
+// smartCase reports whether a query should be matched case-insensitively:
+// only queries without upper case letters ignore case, like less -i.
+func smartCase(query string) bool {
+	for _, r := range query {
+		if unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// findMatches returns every occurrence of query in the visible text of lines.
+// ANSI escape sequences are ignored.
+func findMatches(lines []string, query string) []searchMatch {
+	if query == "" {
+		return nil
+	}
+
+	needle := []rune(query)
+	fold := smartCase(query)
+
+	var matches []searchMatch
+	for i, line := range lines {
+		plain, _ := splitANSI(line)
+		for _, r := range matchRanges(plain, needle, fold) {
+			matches = append(matches, searchMatch{line: i, column: r[0]})
+		}
+	}
+	return matches
+}
+
+// highlightMatches wraps every occurrence of query in the visible text of line
+// in reverse video. Existing escape sequences are preserved; when one of them
+// resets the style in the middle of a match the highlight is re-applied.
+func highlightMatches(line, query string) string {
+	plain, escapes := splitANSI(line)
+	ranges := matchRanges(plain, []rune(query), smartCase(query))
+	if len(ranges) == 0 {
+		return line
+	}
+
+	var b strings.Builder
+	inMatch := false
+	next := 0 // Next range to open
+
+	for i := 0; i <= len(plain); i++ {
+		if inMatch && i == ranges[next-1][1] {
+			b.WriteString(highlightOff)
+			inMatch = false
+		}
+		if !inMatch && next < len(ranges) && i == ranges[next][0] {
+			b.WriteString(highlightOn)
+			inMatch = true
+			next++
+		}
+		for _, esc := range escapes[i] {
+			b.WriteString(esc)
+			if inMatch && strings.HasSuffix(esc, "m") {
+				b.WriteString(highlightOn)
+			}
+		}
+		if i < len(plain) {
+			b.WriteRune(plain[i])
+		}
+	}
+	if inMatch {
+		b.WriteString(highlightOff)
+	}
+
+	return b.String()
+}
+
+// stripANSI removes escape sequences from s.
+func stripANSI(s string) string {
+	plain, _ := splitANSI(s)
+	return string(plain)
+}
+
+// splitANSI separates the visible runes of s from its escape sequences. The
+// returned map holds, for each rune index, the sequences that precede it;
+// sequences at the very end are stored under len(plain).
+func splitANSI(s string) ([]rune, map[int][]string) {
+	plain := make([]rune, 0, len(s))
+	escapes := make(map[int][]string)
+
+	for i := 0; i < len(s); {
+		if s[i] == 0x1b && i+1 < len(s) {
+			end := escapeEnd(s, i)
+			escapes[len(plain)] = append(escapes[len(plain)], s[i:end])
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		plain = append(plain, r)
+		i += size
+	}
+
+	return plain, escapes
+}
+
+// escapeEnd returns the index just past the escape sequence starting at i.
+func escapeEnd(s string, i int) int {
+	switch s[i+1] {
+	case '[': // CSI: parameters and intermediates, then a final byte
+		for j := i + 2; j < len(s); j++ {
+			if s[j] >= 0x40 && s[j] <= 0x7e {
+				return j + 1
+			}
+		}
+		return len(s)
+	case ']': // OSC: terminated by BEL or ST
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == 0x07 {
+				return j + 1
+			}
+			if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 2
+			}
+		}
+		return len(s)
+	default:
+		return i + 2
+	}
+}
+
+// matchRanges returns the non-overlapping [start, end) rune ranges where
+// needle occurs in plain.
+func matchRanges(plain, needle []rune, fold bool) [][2]int {
+	if len(needle) == 0 || len(needle) > len(plain) {
+		return nil
+	}
+
+	var ranges [][2]int
+	for i := 0; i+len(needle) <= len(plain); {
+		if runesEqualAt(plain, i, needle, fold) {
+			ranges = append(ranges, [2]int{i, i + len(needle)})
+			i += len(needle)
+			continue
+		}
+		i++
+	}
+	return ranges
+}
+
+func runesEqualAt(plain []rune, at int, needle []rune, fold bool) bool {
+	for j, r := range needle {
+		c := plain[at+j]
+		if fold {
+			c = unicode.ToLower(c)
+			r = unicode.ToLower(r)
+		}
+		if c != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestFindMatches(t *testing.T) {
+	lines := []string{
+		"Install the CLI",
+		"\x1b[1mRun\x1b[0m the \x1b[38;5;203mcli\x1b[0m",
+		"nothing here",
+		"CLI again, cli",
+	}
+
+	tests := []struct {
+		query    string
+		expected []searchMatch
+	}{
+		{"cli", []searchMatch{{0, 12}, {1, 8}, {3, 0}, {3, 11}}}, // Lower case ignores case
+		{"CLI", []searchMatch{{0, 12}, {3, 0}}},                  // Upper case is exact
+		{"Run the cli", []searchMatch{{1, 0}}},                   // Matches across escape sequences
+		{"missing", nil},                                         // No matches
+		{"", nil},                                                // Empty query matches nothing
+	}
+
+	for _, test := range tests {
+		result := findMatches(lines, test.query)
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("findMatches(%q) = %v, expected %v", test.query, result, test.expected)
+		}
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	// Plain text gets wrapped in reverse video
+	result := highlightMatches("foo bar foo", "foo")
+	expected := highlightOn + "foo" + highlightOff + " bar " + highlightOn + "foo" + highlightOff
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// Styled text keeps its escape sequences and the visible text is unchanged
+	styled := "\x1b[1mHello\x1b[0m \x1b[32mworld\x1b[0m"
+	result = highlightMatches(styled, "lo wo")
+	if stripANSI(result) != stripANSI(styled) {
+		t.Errorf("Highlighting changed visible text: %q", stripANSI(result))
+	}
+	for _, esc := range []string{"\x1b[1m", "\x1b[0m", "\x1b[32m"} {
+		if !strings.Contains(result, esc) {
+			t.Errorf("Expected escape %q to be preserved in %q", esc, result)
+		}
+	}
+
+	// A reset inside the match must re-apply the highlight
+	if !strings.Contains(result, "\x1b[0m"+highlightOn) {
+		t.Errorf("Expected highlight to be re-applied after reset: %q", result)
+	}
+
+	// No match leaves the line untouched
+	if highlightMatches(styled, "xyz") != styled {
+		t.Error("Expected line without matches to be unchanged")
+	}
+}
+
+func TestSearchStateNavigation(t *testing.T) {
+	lines := []string{"a", "match", "b", "match", "c", "match"}
+
+	var s searchState
+	s.query = "match"
+	s.refresh(lines)
+
+	if line, ok := s.jumpFrom(2, false); !ok || line != 3 {
+		t.Errorf("Expected forward jump to line 3, got %d (%t)", line, ok)
+	}
+	if line, _ := s.next(3, false); line != 5 {
+		t.Errorf("Expected n to move to line 5, got %d", line)
+	}
+	if line, _ := s.next(5, false); line != 1 {
+		t.Errorf("Expected n to wrap to line 1, got %d", line)
+	}
+	if line, _ := s.next(1, true); line != 5 {
+		t.Errorf("Expected N to wrap back to line 5, got %d", line)
+	}
+
+	if line, _ := s.jumpFrom(2, true); line != 1 {
+		t.Errorf("Expected backward jump to line 1, got %d", line)
+	}
+
+	if status := s.status(); !strings.Contains(status, "match 1 of 3") {
+		t.Errorf("Expected match count in status, got %q", status)
+	}
+
+	// Every occurrence on a line is a match of its own
+	s.query = "a"
+	s.refresh([]string{"banana", "b", "a"})
+	if status := s.status(); !strings.Contains(status, "(4 matches)") {
+		t.Errorf("Expected occurrences to be counted, got %q", status)
+	}
+	s.jumpFrom(0, false)
+	for i, want := range []int{0, 0, 2, 0} {
+		if line, _ := s.next(0, false); line != want || s.current != (i+1)%4 {
+			t.Errorf("Expected n #%d on line %d, got line %d (current %d)", i+1, want, line, s.current)
+		}
+	}
+}
+
+func TestSingleFileSearch(t *testing.T) {
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = "filler"
+	}
+	lines[30] = "the needle"
+	lines[40] = "another needle"
+
+	model := &SingleFileModel{
+		lines:  lines,
+		height: 10,
+		width:  80,
+	}
+
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune{'/'}},
+		{Type: tea.KeyRunes, Runes: []rune("needle")},
+		{Type: tea.KeyEnter},
+	}
+	for _, key := range keys {
+		updated, _ := model.Update(key)
+		model = updated.(*SingleFileModel)
+	}
+
+	if model.viewport != 30 {
+		t.Errorf("Expected viewport at first match (30), got %d", model.viewport)
+	}
+	if !strings.Contains(model.statusLine(), "match 1 of 2") {
+		t.Errorf("Expected match count in status line, got %q", model.statusLine())
+	}
+
+	updated, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	model = updated.(*SingleFileModel)
+	if model.viewport != 40 {
+		t.Errorf("Expected n to jump to line 40, got %d", model.viewport)
+	}
+
+	if !strings.Contains(model.View(), highlightOn+"needle"+highlightOff) {
+		t.Error("Expected match to be highlighted in the view")
+	}
+
+	// Esc clears the search instead of quitting
+	updated, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = updated.(*SingleFileModel)
+	if cmd != nil {
+		t.Error("Expected esc to clear the search, not quit")
+	}
+	if model.statusLine() != "" {
+		t.Errorf("Expected status line to be hidden after clearing, got %q", model.statusLine())
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+type seqParticipant struct {
+	id     string
+	label  string
+	width  int
+	center int
+}
+
+// Kinds of sequence diagram events.
+const (
+	seqMessage = iota
+	seqNote
+	seqDivider // The start, middle or end of a loop, alt or other block
+)
+
+type seqEvent struct {
+	kind     int
+	from, to int // Participants; a note over several spans from to to
+	text     string
+	dotted   bool
+	head     rune // Arrowhead at the receiving end, 0 for none
+	both     bool // Arrowheads at both ends
+	position string
+}
+
+// sequence is a parsed sequence diagram.
+type sequence struct {
+	participants []*seqParticipant
+	events       []seqEvent
+	blocks       []string // Open blocks, innermost last
+}
+
+var (
+	seqParticipantPattern = regexp.MustCompile(`^(?:create\s+)?(?:participant|actor)\s+(.+?)(?:\s+as\s+(.+))?$`)
+	seqMessagePattern     = regexp.MustCompile(`^([^-<>:]+?)\s*(<<)?(--?)(>>|>|x|\))\s*[+-]?\s*([^:]+?)\s*(?::\s*(.*))?$`)
+	seqNotePattern        = regexp.MustCompile(`(?i)^note\s+(left of|right of|over)\s+([^:]+?)\s*:\s*(.*)$`)
+)
+
+// seqBlocks start a block drawn as a divider with their text; else, and
+// and option divide one. rect and box only color their contents.
+var seqBlocks = []string{"loop", "alt", "else", "opt", "par", "and", "critical", "option", "break", "rect"}
+
+// seqSkipped are statements that don't affect the drawing.
+var seqSkipped = []string{"activate", "deactivate", "destroy", "title", "accTitle", "accDescr", "link", "links", "properties", "details", "box"}
+
+// renderSequence draws a sequence diagram: participants side by side with
+// their lifelines below, and messages and notes in order down the page. ok
+// is false if a statement can't be parsed.
+func renderSequence(lines []string) (string, bool) {
+	s := &sequence{}
+	numbered := false
+	for _, line := range lines {
+		keyword := strings.TrimRight(strings.Fields(line)[0], ":")
+		switch {
+		case keyword == "autonumber":
+			numbered = true
+		case keyword == "end":
+			if len(s.blocks) > 0 && s.blocks[len(s.blocks)-1] != "rect" {
+				s.events = append(s.events, seqEvent{kind: seqDivider})
+			}
+			if len(s.blocks) > 0 {
+				s.blocks = s.blocks[:len(s.blocks)-1]
+			}
+		case slices.Contains(seqBlocks, keyword):
+			if keyword != "else" && keyword != "and" && keyword != "option" {
+				s.blocks = append(s.blocks, keyword)
+			}
+			if keyword != "rect" {
+				s.events = append(s.events, seqEvent{kind: seqDivider, text: line})
+			}
+		case slices.Contains(seqSkipped, keyword):
+		default:
+			if !s.parse(line) {
+				return "", false
+			}
+		}
+	}
+	if len(s.participants) == 0 {
+		return "", false
+	}
+
+	if numbered {
+		n := 0
+		for i := range s.events {
+			if s.events[i].kind == seqMessage {
+				n++
+				s.events[i].text = strconv.Itoa(n) + ". " + s.events[i].text
+			}
+		}
+	}
+	return s.draw(), true
+}
+
+// parse adds a participant, message or note.
+func (s *sequence) parse(line string) bool {
+	if match := seqParticipantPattern.FindStringSubmatch(line); match != nil {
+		p := s.participant(match[1])
+		if match[2] != "" {
+			p.label = mermaidText(match[2])
+		}
+		return true
+	}
+
+	if match := seqMessagePattern.FindStringSubmatch(line); match != nil {
+		event := seqEvent{
+			kind:   seqMessage,
+			from:   s.index(match[1]),
+			to:     s.index(match[5]),
+			text:   mermaidText(match[6]),
+			dotted: match[3] == "--",
+			both:   match[2] != "",
+		}
+		switch match[4] {
+		case ">>", ")":
+			event.head = arrowGlyph(linkRight)
+		case "x":
+			event.head = '×'
+			if glyphs.ascii {
+				event.head = 'x'
+			}
+		}
+		s.events = append(s.events, event)
+		return true
+	}
+
+	if match := seqNotePattern.FindStringSubmatch(line); match != nil {
+		event := seqEvent{kind: seqNote, position: strings.ToLower(match[1]), text: mermaidText(match[3])}
+		names := strings.Split(match[2], ",")
+		event.from = s.index(names[0])
+		event.to = s.index(names[len(names)-1])
+		if event.from > event.to {
+			event.from, event.to = event.to, event.from
+		}
+		s.events = append(s.events, event)
+		return true
+	}
+	return false
+}
+
+// participant returns the participant with an ID, adding it the first time
+// it is seen.
+func (s *sequence) participant(id string) *seqParticipant {
+	id = strings.TrimSpace(id)
+	for _, p := range s.participants {
+		if p.id == id {
+			return p
+		}
+	}
+	p := &seqParticipant{id: id, label: id}
+	s.participants = append(s.participants, p)
+	return p
+}
+
+func (s *sequence) index(id string) int {
+	return slices.Index(s.participants, s.participant(id))
+}
+
+// draw spaces the participants so that messages and notes fit between them
+// and draws the diagram.
+func (s *sequence) draw() string {
+	ps := s.participants
+	for _, p := range ps {
+		p.width = runewidth.StringWidth(p.label) + 4
+	}
+
+	// gaps[i] is the distance from the center of participant i to the next
+	gaps := make([]int, len(ps))
+	for i := 0; i < len(ps)-1; i++ {
+		gaps[i] = ps[i].width/2 + ps[i+1].width/2 + 3
+	}
+	left := ps[0].width / 2
+	widen := func(from, to, need int) {
+		if from == to {
+			return
+		}
+		have := 0
+		for i := from; i < to; i++ {
+			have += gaps[i]
+		}
+		gaps[to-1] += max(need-have, 0)
+	}
+	for _, e := range s.events {
+		width := runewidth.StringWidth(e.text)
+		switch {
+		case e.kind == seqMessage && e.from == e.to:
+			widen(e.from, min(e.from+1, len(ps)-1), width+7)
+		case e.kind == seqMessage:
+			widen(min(e.from, e.to), max(e.from, e.to), width+4)
+		case e.kind == seqNote && e.position == "right of":
+			widen(e.from, min(e.from+1, len(ps)-1), width+7)
+		case e.kind == seqNote && e.position == "left of" && e.from == 0:
+			left = max(left, width+6)
+		case e.kind == seqNote && e.position == "left of":
+			widen(e.from-1, e.from, width+7)
+		case e.kind == seqNote && e.from == e.to:
+			left = max(left, (width+4)/2)
+		case e.kind == seqNote:
+			widen(e.from, e.to, width)
+		}
+	}
+	ps[0].center = left
+	for i := 1; i < len(ps); i++ {
+		ps[i].center = ps[i-1].center + gaps[i-1]
+	}
+	last := ps[len(ps)-1]
+	right := last.center + last.width/2
+
+	c := &canvas{}
+	joint := []rune("┬┴")
+	if glyphs.ascii {
+		joint = []rune("++")
+	}
+	for _, p := range ps {
+		c.box(p.center-p.width/2, 0, p.width, 3, p.label, false)
+		c.put(p.center, 2, joint[0])
+	}
+
+	y := 3
+	for _, e := range s.events {
+		from, to := ps[e.from].center, ps[e.to].center
+		width := runewidth.StringWidth(e.text)
+
+		switch {
+		case e.kind == seqMessage && e.from == e.to:
+			c.hline(from, from+3, y, e.dotted)
+			c.vline(from+3, y, y+1, e.dotted)
+			c.hline(from+1, from+3, y+1, e.dotted)
+			if e.head != 0 {
+				c.put(from+1, y+1, s.pointing(e.head, linkLeft))
+			}
+			c.write(from+5, y, e.text)
+			y += 2
+
+		case e.kind == seqMessage:
+			c.write(min(from, to)+(abs(to-from)-width)/2, y, e.text)
+			c.hline(from, to, y+1, e.dotted)
+			step := 1
+			if to < from {
+				step = -1
+			}
+			if e.head != 0 {
+				c.put(to-step, y+1, s.pointing(e.head, directionOf(step)))
+				if e.both {
+					c.put(from+step, y+1, s.pointing(e.head, directionOf(-step)))
+				}
+			}
+			y += 2
+
+		case e.kind == seqNote:
+			x, boxWidth := from-(width+4)/2, width+4
+			switch e.position {
+			case "right of":
+				x = from + 2
+			case "left of":
+				x = from - 1 - boxWidth
+			default:
+				if e.from != e.to {
+					x, boxWidth = from-2, max(boxWidth, to-from+5)
+				}
+			}
+			c.box(x, y, boxWidth, 3, e.text, false)
+			y += 3
+
+		default:
+			c.hline(0, right, y, true)
+			if e.text != "" {
+				c.write(1, y, " "+e.text+" ")
+			}
+			y++
+		}
+	}
+
+	for _, p := range ps {
+		c.vline(p.center, 3, y, false)
+		c.box(p.center-p.width/2, y, p.width, 3, p.label, false)
+		c.put(p.center, y, joint[1])
+	}
+	return c.String()
+}
+
+// pointing turns an arrowhead to point in a direction; other heads are
+// returned as they are.
+func (s *sequence) pointing(head rune, direction uint8) rune {
+	if head == arrowGlyph(linkRight) {
+		return arrowGlyph(direction)
+	}
+	return head
+}
+
+func directionOf(step int) uint8 {
+	if step < 0 {
+		return linkLeft
+	}
+	return linkRight
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderSequence(t *testing.T) {
+	got, ok := renderMermaid("sequenceDiagram\n  Alice->>Bob: Hi\n  Bob-->>Alice: Hey")
+	if !ok {
+		t.Fatal("Expected the sequence diagram to be drawn")
+	}
+	expected := strings.Join([]string{
+		"┌───────┐  ┌─────┐",
+		"│ Alice │  │ Bob │",
+		"└───┬───┘  └──┬──┘",
+		"    │   Hi    │",
+		"    ├────────▶┤",
+		"    │  Hey    │",
+		"    ├◀┄┄┄┄┄┄┄┄┤",
+		"┌───┴───┐  ┌──┴──┐",
+		"│ Alice │  │ Bob │",
+		"└───────┘  └─────┘",
+	}, "\n")
+	if got != expected {
+		t.Errorf("Unexpected drawing:\n%s\n\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRenderSequenceBlocksAndNotes(t *testing.T) {
+	source := strings.Join([]string{
+		"sequenceDiagram",
+		"  autonumber",
+		"  participant C as Client",
+		"  actor U as User",
+		"  participant S as Server",
+		"  activate S",
+		"  C->>+S: A request with a long description",
+		"  loop Every minute",
+		"    S->>S: Refresh the cache",
+		"  end",
+		"  Note right of S: Cached",
+		"  Note over C,U: Both wait",
+		"  S--xU: Lost",
+		"  rect rgb(0, 0, 255)",
+		"    S--)C: Done",
+		"  end",
+	}, "\n")
+	got, ok := renderMermaid(source)
+	if !ok {
+		t.Fatal("Expected the sequence diagram to be drawn")
+	}
+	lines := strings.Split(got, "\n")
+
+	// Participants are in the order they were declared
+	header := lines[1]
+	if !(strings.Index(header, "Client") < strings.Index(header, "User") && strings.Index(header, "User") < strings.Index(header, "Server")) {
+		t.Errorf("Expected the participants in order, got %q", header)
+	}
+
+	for _, text := range []string{
+		"1. A request with a long description",
+		"2. Refresh the cache",
+		"3. Lost",
+		"4. Done",
+		"loop Every minute",
+		"│ Cached │",
+		"Both wait",
+		"×",
+	} {
+		if !strings.Contains(got, text) {
+			t.Errorf("Expected %q in the drawing:\n%s", text, got)
+		}
+	}
+	if strings.Contains(got, "rect") {
+		t.Errorf("Expected rect blocks not to be shown:\n%s", got)
+	}
+
+	// The request spans the user's lifeline and the message text fits
+	// between the participants
+	for i, line := range lines {
+		if strings.Contains(line, "1. A request") && !strings.Contains(lines[i+1], "┼") {
+			t.Errorf("Expected the request to cross the user's lifeline:\n%s", got)
+		}
+	}
+}
+
+func TestParseSequenceMessages(t *testing.T) {
+	s := &sequence{}
+	for _, line := range []string{"A->B: solid", "A-->>B", "B<<->>A: both ways", "B-xA: lost"} {
+		if !s.parse(line) {
+			t.Fatalf("Failed to parse %q", line)
+		}
+	}
+	if len(s.participants) != 2 || len(s.events) != 4 {
+		t.Fatalf("Expected 2 participants and 4 messages, got %d and %d", len(s.participants), len(s.events))
+	}
+
+	tests := []struct {
+		from, to int
+		text     string
+		dotted   bool
+		head     rune
+		both     bool
+	}{
+		{0, 1, "solid", false, 0, false},
+		{0, 1, "", true, '▶', false},
+		{1, 0, "both ways", false, '▶', true},
+		{1, 0, "lost", false, '×', false},
+	}
+	for i, test := range tests {
+		e := s.events[i]
+		if e.from != test.from || e.to != test.to || e.text != test.text || e.dotted != test.dotted || e.head != test.head || e.both != test.both {
+			t.Errorf("Message %d: unexpected %+v", i, e)
+		}
+	}
+}
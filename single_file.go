@@ -1,19 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
-)
-
-var (
-	rendererCache = make(map[int]*glamour.TermRenderer)
-	rendererMutex sync.RWMutex
+	"github.com/charmbracelet/lipgloss"
 )
 
 type SingleFileModel struct {
@@ -24,9 +22,36 @@ type SingleFileModel struct {
 	width           int
 	height          int
 	renderer        *glamour.TermRenderer
-	raw             bool // Toggle between raw and rendered view
-	contentLoaded   bool // Track if content has been loaded
-	rendererCreated bool // Track if renderer has been created
+	raw             bool   // Toggle between raw and rendered view
+	hideMeta        bool   // Leave the front matter header out of the rendered view
+	style           string // Rendering style, see renderStyle
+	contentLoaded   bool   // Track if content has been loaded
+	rendererCreated bool   // Track if renderer has been created
+	search          searchState
+	outline         picker
+	outlineLines    []int // Display line of each heading in the open outline
+	links           []Link
+	linkPicker      picker
+	history         navHistory
+	pending         *pendingPosition // Where to scroll once the next document is shown
+	preloadedPath   string           // Name of content passed in directly, such as stdin
+	preloaded       string
+	message         string // Transient message for the status line
+	messageID       int    // Incremented whenever a timed message is shown
+	stamp           fileStamp
+	watching        bool // True once the file watch loop is running
+	stream          <-chan streamChunk
+	stopStream      context.CancelFunc // Stops reading the stream
+	streaming       bool               // True while input is still arriving
+	follow          bool               // Keep the view pinned to the end as input arrives
+	renderScheduled bool               // A throttled re-render of streamed input is pending
+}
+
+// pendingPosition is a scroll target applied after a document has loaded,
+// either a heading anchor or a viewport remembered in the history.
+type pendingPosition struct {
+	anchor   string
+	viewport int
 }
 
 func NewSingleFileModel(filepath string) (*SingleFileModel, error) {
@@ -35,8 +60,9 @@ func NewSingleFileModel(filepath string) (*SingleFileModel, error) {
 		filepath:        filepath,
 		content:         "", // Will be loaded lazily
 		viewport:        0,
-		renderer:        nil,                         // Will be created lazily when needed
-		raw:             false,                       // Default to rendered mode
+		renderer:        nil,   // Will be created lazily when needed
+		raw:             false, // Default to rendered mode
+		style:           renderStyle,
 		lines:           []string{"Loading file..."}, // Placeholder
 		contentLoaded:   false,
 		rendererCreated: false,
@@ -51,37 +77,80 @@ func NewSingleFileModelWithContent(filepath string, content string) (*SingleFile
 		filepath:        filepath,
 		content:         content,
 		viewport:        0,
-		renderer:        nil,                    // Will be created lazily when needed
-		raw:             false,                  // Default to rendered mode
+		renderer:        nil,   // Will be created lazily when needed
+		raw:             false, // Default to rendered mode
+		style:           renderStyle,
 		lines:           []string{"Loading..."}, // Will be replaced immediately
 		contentLoaded:   true,                   // Content is already available
 		rendererCreated: false,                  // Renderer still needs to be created
+		preloadedPath:   filepath,               // Kept so history can return to it
+		preloaded:       content,
+	}
+
+	return m, nil
+}
+
+// NewSingleFileModelWithStream views input as it is read from r, such as a
+// pipe into stdin. The view follows the end of the input until the user
+// scrolls up.
+func NewSingleFileModelWithStream(filepath string, r io.Reader) (*SingleFileModel, error) {
+	ctx, stop := context.WithCancel(context.Background())
+	m := &SingleFileModel{
+		filepath:      filepath,
+		lines:         []string{""},
+		contentLoaded: true,
+		preloadedPath: filepath,
+		style:         renderStyle,
+		stream:        readStream(ctx, r),
+		stopStream:    stop,
+		streaming:     true,
+		follow:        true,
 	}
 
 	return m, nil
 }
 
 func (m *SingleFileModel) Init() tea.Cmd {
+	if m.stream != nil {
+		return waitForChunk(m.stream)
+	}
+
 	// If content is already loaded (stdin), don't load from file
 	if m.contentLoaded {
 		return func() tea.Msg {
-			return fileLoadedMsg{content: m.content, err: nil}
+			return fileLoadedMsg{path: m.filepath, content: m.content, err: nil}
+		}
+	}
+
+	return m.loadCmd(m.filepath)
+}
+
+// loadCmd reads a document in the background. Content that was passed in
+// directly is served from memory.
+func (m *SingleFileModel) loadCmd(path string) tea.Cmd {
+	if path == m.preloadedPath {
+		content := m.preloaded
+		return func() tea.Msg {
+			return fileLoadedMsg{path: path, content: content, err: nil}
 		}
 	}
 
 	// Load file content in true background goroutine
 	return tea.Tick(1, func(t time.Time) tea.Msg {
 		// This runs in a separate goroutine, not blocking UI
-		content, err := os.ReadFile(m.filepath)
+		stamp := statFile(path)
+		content, err := os.ReadFile(path)
 		if err != nil {
-			return fileLoadedMsg{content: "", err: err}
+			return fileLoadedMsg{path: path, content: "", err: err}
 		}
-		return fileLoadedMsg{content: string(content), err: nil}
+		return fileLoadedMsg{path: path, content: string(content), stamp: stamp, err: nil}
 	})
 }
 
 type fileLoadedMsg struct {
+	path    string // Empty for messages that don't belong to a specific file
 	content string
+	stamp   fileStamp // Version of the file that was read
 	err     error
 }
 
@@ -89,68 +158,98 @@ type renderContentMsg struct{}
 
 type rendererCreatedMsg struct {
 	renderer *glamour.TermRenderer
+	style    string
 	err      error
 }
 
 type contentRenderedMsg struct {
-	lines []string
-	err   error
+	lines    []string
+	source   string         // Content the lines were rendered from
+	settings renderSettings // How the content was rendered
+	err      error
 }
 
-func createRendererInBackground(width int) tea.Cmd {
-	return tea.Tick(1, func(t time.Time) tea.Msg {
-		// Check cache first
-		rendererMutex.RLock()
-		if cached, exists := rendererCache[width]; exists {
-			rendererMutex.RUnlock()
-			return rendererCreatedMsg{renderer: cached, err: nil}
-		}
-		rendererMutex.RUnlock()
-
-		// Create renderer with fast dark style
-		renderer, err := glamour.NewTermRenderer(
-			glamour.WithStandardStyle("dark"),
-			glamour.WithWordWrap(width),
-		)
-
-		// Cache successful renderer
-		if err == nil {
-			rendererMutex.Lock()
-			rendererCache[width] = renderer
-			rendererMutex.Unlock()
-		}
+// renderSettings are the view options a render depends on besides the
+// content. Raw lines don't depend on the style or front matter.
+type renderSettings struct {
+	raw   bool
+	style string
+	meta  bool
+}
 
-		return rendererCreatedMsg{renderer: renderer, err: err}
+func createRendererInBackground(style string, width int) tea.Cmd {
+	return tea.Tick(1, func(t time.Time) tea.Msg {
+		renderer, err := cachedRenderer(style, width)
+		return rendererCreatedMsg{renderer: renderer, style: style, err: err}
 	})
 }
 
-func renderContentAsync(content string, renderer *glamour.TermRenderer, raw bool) tea.Cmd {
+func renderContentAsync(path, content string, renderer *glamour.TermRenderer, settings renderSettings) tea.Cmd {
 	return tea.Tick(1, func(t time.Time) tea.Msg {
-		if raw || renderer == nil || content == "" {
-			return contentRenderedMsg{lines: strings.Split(content, "\n"), err: nil}
+		if settings.raw || renderer == nil || content == "" {
+			return contentRenderedMsg{lines: strings.Split(content, "\n"), source: content, settings: settings, err: nil}
 		}
 
-		rendered, err := renderer.Render(content)
+		rendered, err := renderer.Render(prepareForRender(path, content, settings.meta))
 		if err != nil {
-			return contentRenderedMsg{lines: strings.Split(content, "\n"), err: err}
+			return contentRenderedMsg{lines: strings.Split(content, "\n"), source: content, settings: settings, err: err}
 		}
 
-		return contentRenderedMsg{lines: strings.Split(rendered, "\n"), err: nil}
+		return contentRenderedMsg{lines: strings.Split(rendered, "\n"), source: content, settings: settings, err: nil}
 	})
 }
 
+// renderSettings returns the view options the document is currently shown
+// with.
+func (m *SingleFileModel) renderSettings() renderSettings {
+	if m.raw {
+		return renderSettings{raw: true}
+	}
+	return renderSettings{style: m.style, meta: !m.hideMeta}
+}
+
+// renderContent renders the document in the background as currently shown.
+func (m *SingleFileModel) renderContent() tea.Cmd {
+	return renderContentAsync(m.filepath, m.content, m.renderer, m.renderSettings())
+}
+
 func (m *SingleFileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case fileLoadedMsg:
+		if msg.path != "" && msg.path != m.filepath {
+			// Stale result for a document we navigated away from
+			return m, nil
+		}
 		if msg.err != nil {
 			m.lines = []string{fmt.Sprintf("Error loading file: %v", msg.err)}
 			return m, nil
 		}
 		m.content = msg.content
 		m.contentLoaded = true
+		m.stamp = msg.stamp
+
+		// Reopening a file resumes where it was left, unless it has changed
+		if m.pending == nil && m.filepath != m.preloadedPath {
+			if state, ok := lookupFileState(m.filepath, m.content); ok {
+				m.raw = state.Raw
+				m.pending = &pendingPosition{viewport: state.Viewport}
+			}
+		}
 
 		// Show raw content immediately for instant display
 		m.lines = strings.Split(m.content, "\n")
+		m.search.refresh(m.lines)
+		m.applyPending()
+		if m.raw {
+			m.pending = nil
+		}
+
+		// Watch the file for changes from now on
+		var watch tea.Cmd
+		if !m.watching && m.watchPath() != "" {
+			m.watching = true
+			watch = watchFile(m.watchPath(), m.stamp)
+		}
 
 		// Start async renderer creation if needed
 		if !m.raw && m.renderer == nil {
@@ -158,17 +257,36 @@ func (m *SingleFileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.width > 0 {
 				width = m.width
 			}
-			return m, createRendererInBackground(width)
+			return m, tea.Batch(watch, createRendererInBackground(m.style, width))
 		}
 
 		// If we already have a renderer, start async rendering
 		if !m.raw && m.renderer != nil {
-			return m, renderContentAsync(m.content, m.renderer, m.raw)
+			return m, tea.Batch(watch, m.renderContent())
 		}
 
+		return m, watch
+
+	case fileWatchMsg:
+		var cmd tea.Cmd
+		if msg.path != "" && msg.path == m.watchPath() {
+			if msg.changed && msg.content != m.content {
+				cmd = m.reload(msg.content)
+			}
+			m.stamp = msg.stamp
+		}
+		return m, tea.Batch(cmd, watchFile(m.watchPath(), m.stamp))
+
+	case clearStatusMsg:
+		if msg.id == m.messageID {
+			m.message = ""
+		}
 		return m, nil
 
 	case rendererCreatedMsg:
+		if msg.style != m.style {
+			return m, nil // The style was changed again in the meantime
+		}
 		if msg.err != nil {
 			// Renderer creation failed - stay in raw mode
 			return m, nil
@@ -178,11 +296,23 @@ func (m *SingleFileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Now that renderer is ready, start content rendering
 		if !m.raw && m.content != "" {
-			return m, renderContentAsync(m.content, m.renderer, m.raw)
+			return m, m.renderContent()
 		}
+		m.renderScheduled = false
 		return m, nil
 
 	case contentRenderedMsg:
+		m.renderScheduled = false
+		// Renders of a document that has since been left, or made before the
+		// raw, style or front matter setting changed, are dropped.
+		// Streamed input only grows, so what had arrived is shown meanwhile
+		if msg.settings != m.renderSettings() {
+			return m, nil
+		}
+		if msg.source != m.content && (m.stream == nil || msg.source == "" || !strings.HasPrefix(m.content, msg.source)) {
+			return m, nil
+		}
+
 		if msg.err == nil {
 			// Successfully rendered
 			m.lines = msg.lines
@@ -190,12 +320,34 @@ func (m *SingleFileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// If rendering failed, fall back to raw content
 			m.lines = strings.Split(m.content, "\n")
 		}
+		m.search.refresh(m.lines)
+		m.applyPending()
+		m.pending = nil
+		m.followEnd()
+
+		// Input that arrived while rendering needs another pass
+		if m.stream != nil && msg.source != m.content && !m.raw {
+			return m, m.scheduleStreamRender()
+		}
 		return m, nil
 
+	case streamChunkMsg:
+		return m, m.appendStream(msg)
+
+	case streamRenderMsg:
+		if m.renderer == nil {
+			width := 80
+			if m.width > 0 {
+				width = m.width
+			}
+			return m, createRendererInBackground(m.style, width)
+		}
+		return m, m.renderContent()
+
 	case renderContentMsg:
 		// Manual refresh trigger
 		if m.content != "" && m.renderer != nil {
-			return m, renderContentAsync(m.content, m.renderer, m.raw)
+			return m, m.renderContent()
 		}
 		return m, nil
 
@@ -206,60 +358,177 @@ func (m *SingleFileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Re-create renderer with new width
 		if !m.raw && m.width > 0 && m.content != "" {
 			m.renderer = nil // Force recreation with new width
-			return m, createRendererInBackground(m.width)
+			return m, createRendererInBackground(m.style, m.width)
 		}
 		return m, nil
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c", "esc":
-			return m, tea.Quit
+		cmd := m.handleKey(msg)
+		// Scrolling away from the end stops following new input
+		if m.follow && m.viewport < m.bottom() {
+			m.follow = false
+		}
+		return m, cmd
+	}
 
-		case "j", "down":
-			if m.viewport < len(m.lines)-m.height {
-				m.viewport++
-			}
+	return m, nil
+}
 
-		case "k", "up":
-			if m.viewport > 0 {
-				m.viewport--
-			}
+// handleKey processes a key press and returns the command it triggers.
+func (m *SingleFileModel) handleKey(msg tea.KeyMsg) tea.Cmd {
+	m.message = ""
+	if m.outline.active {
+		_, popupHeight := m.popupSize(len(m.outline.items))
+		if index := m.outline.handleKey(msg, m.outline.visibleRows(popupHeight)); index >= 0 {
+			m.scrollTo(m.outlineLines[index])
+		}
+		return nil
+	}
+	if m.linkPicker.active {
+		_, popupHeight := m.popupSize(len(m.linkPicker.items))
+		if index := m.linkPicker.handleKey(msg, m.linkPicker.visibleRows(popupHeight)); index >= 0 {
+			return m.followLink(m.links[index])
+		}
+		return nil
+	}
+	if m.search.typing {
+		m.updateSearchInput(msg)
+		return nil
+	}
 
-		case "ctrl+d", "pgdown":
-			m.viewport += m.height / 2
-			if m.viewport > len(m.lines)-m.height {
-				m.viewport = max(0, len(m.lines)-m.height)
-			}
+	height := m.contentHeight()
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m.quit()
 
-		case "ctrl+u", "pgup":
-			m.viewport -= m.height / 2
-			if m.viewport < 0 {
-				m.viewport = 0
-			}
+	case "esc":
+		// Esc first dismisses an active search, then quits
+		if m.search.query != "" {
+			m.search.clear()
+			return nil
+		}
+		return m.quit()
+
+	case "j", "down":
+		if m.viewport < len(m.lines)-height {
+			m.viewport++
+		}
+
+	case "k", "up":
+		if m.viewport > 0 {
+			m.viewport--
+		}
+
+	case "ctrl+d", "pgdown":
+		m.viewport += height / 2
+		if m.viewport > len(m.lines)-height {
+			m.viewport = max(0, len(m.lines)-height)
+		}
 
-		case "g", "home":
+	case "ctrl+u", "pgup":
+		m.viewport -= height / 2
+		if m.viewport < 0 {
 			m.viewport = 0
+		}
 
-		case "G", "end":
-			m.viewport = max(0, len(m.lines)-m.height)
+	case "g", "home":
+		m.viewport = 0
 
-		case "r":
-			// Toggle raw/rendered view
-			m.raw = !m.raw
-			if m.content != "" && m.renderer != nil {
-				return m, renderContentAsync(m.content, m.renderer, m.raw)
-			}
+	case "G", "end":
+		m.viewport = max(0, len(m.lines)-height)
+
+	case "F":
+		// Follow the end of the input, like less +F
+		m.follow = true
+		m.followEnd()
 
-		case " ":
-			// Space for page down
-			m.viewport += m.height - 1
-			if m.viewport > len(m.lines)-m.height {
-				m.viewport = max(0, len(m.lines)-m.height)
+	case "r":
+		// Toggle raw/rendered view
+		m.raw = !m.raw
+		if m.raw {
+			// Raw lines need no renderer, which may still be on its way
+			m.lines = strings.Split(m.content, "\n")
+			m.search.refresh(m.lines)
+			m.viewport = min(m.viewport, m.bottom())
+			return nil
+		}
+		if m.content != "" && m.renderer != nil {
+			return m.renderContent()
+		}
+		// A file reopened in raw mode has no renderer yet
+		if m.content != "" {
+			width := 80
+			if m.width > 0 {
+				width = m.width
 			}
+			return createRendererInBackground(m.style, width)
+		}
+
+	case "m":
+		// Toggle the front matter header
+		m.hideMeta = !m.hideMeta
+		m.messageID++
+		m.message = metaMessage(m.hideMeta)
+		hide := clearStatusAfter(m.messageID)
+		if m.raw || m.renderer == nil || m.content == "" {
+			return hide
+		}
+		return tea.Batch(hide, m.renderContent())
+
+	case "t":
+		// Cycle rendering styles
+		m.style = nextStyle(m.style)
+		m.renderer = nil
+		m.messageID++
+		m.message = "Style: " + styleName(m.style)
+		hide := clearStatusAfter(m.messageID)
+		if m.raw || m.content == "" {
+			return hide
+		}
+		width := 80
+		if m.width > 0 {
+			width = m.width
+		}
+		return tea.Batch(hide, createRendererInBackground(m.style, width))
+
+	case " ":
+		// Space for page down
+		m.viewport += height - 1
+		if m.viewport > len(m.lines)-height {
+			m.viewport = max(0, len(m.lines)-height)
+		}
+
+	case "/", "?":
+		m.search.begin(msg.String() == "?", m.viewport)
+
+	case "n", "N":
+		if line, ok := m.search.next(m.viewport, msg.String() == "N"); ok {
+			m.scrollTo(line)
+		}
+
+	case "o":
+		// Show the document outline
+		headings := ParseHeadings(m.content)
+		m.outlineLines = headingDisplayLines(headings, m.lines, m.raw, strings.Count(m.content, "\n")+1)
+		m.outline.open("Outline", outlineItems(headings), currentHeading(m.outlineLines, m.viewport))
+
+	case "f":
+		// Pick a link to follow
+		m.links = InternalLinks(m.content)
+		m.linkPicker.open("Links", linkItems(m.links), 0)
+
+	case "[", "backspace":
+		if entry, ok := m.history.back(historyEntry{path: m.filepath, viewport: m.viewport}); ok {
+			return m.openPath(entry.path, &pendingPosition{viewport: entry.viewport})
+		}
+
+	case "]":
+		if entry, ok := m.history.forward(historyEntry{path: m.filepath, viewport: m.viewport}); ok {
+			return m.openPath(entry.path, &pendingPosition{viewport: entry.viewport})
 		}
 	}
 
-	return m, nil
+	return nil
 }
 
 func (m *SingleFileModel) View() string {
@@ -269,14 +538,259 @@ func (m *SingleFileModel) View() string {
 
 	// Simple content view without heavy status bar
 	var content strings.Builder
-	endLine := min(m.viewport+m.height, len(m.lines))
+	height := m.contentHeight()
+	endLine := min(m.viewport+height, len(m.lines))
 
 	for i := m.viewport; i < endLine; i++ {
-		content.WriteString(m.lines[i])
+		content.WriteString(m.search.highlight(m.lines[i]))
 		if i < endLine-1 {
 			content.WriteString("\n")
 		}
 	}
 
+	// The status line only appears while there is something to report
+	if status := m.statusLine(); status != "" {
+		for i := endLine - m.viewport; i < height; i++ {
+			content.WriteString("\n")
+		}
+		statusStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color("235")).
+			Foreground(lipgloss.Color("250")).
+			Width(m.width)
+		content.WriteString("\n" + statusStyle.Render(status))
+	}
+
+	for _, popup := range []*picker{&m.outline, &m.linkPicker} {
+		if !popup.active {
+			continue
+		}
+		// Pad to full height so the popup has something to sit on
+		view := content.String()
+		if lines := strings.Count(view, "\n") + 1; lines < m.height {
+			view += strings.Repeat("\n", m.height-lines)
+		}
+		width, height := m.popupSize(len(popup.items))
+		return centerOverlay(popup.view(width, height), view, m.width, m.height)
+	}
+
 	return content.String()
 }
+
+// popupSize returns the dimensions of a popup such as the outline listing
+// the given number of items.
+func (m *SingleFileModel) popupSize(items int) (int, int) {
+	return min(60, max(20, m.width-4)), max(5, min(m.height-2, items+3))
+}
+
+// followLink opens the target of a link, pushing the current position onto
+// the history.
+func (m *SingleFileModel) followLink(link Link) tea.Cmd {
+	path, anchor := resolveLink(treeFS{}, m.filepath, link.Target)
+	if path == "" {
+		path = m.filepath
+	} else if _, err := os.Stat(path); err != nil {
+		m.message = "Link target not found: " + link.Target
+		return nil
+	}
+
+	m.history.visit(historyEntry{path: m.filepath, viewport: m.viewport})
+	return m.openPath(path, &pendingPosition{anchor: anchor})
+}
+
+// openPath shows the document at path and scrolls to pending once it is
+// available. Positions within the current document apply immediately.
+func (m *SingleFileModel) openPath(path string, pending *pendingPosition) tea.Cmd {
+	m.pending = pending
+	if path == m.filepath && m.contentLoaded {
+		m.applyPending()
+		m.pending = nil
+		return nil
+	}
+
+	m.rememberPosition()
+	m.filepath = path
+	m.content = ""
+	m.contentLoaded = false
+	m.lines = []string{"Loading file..."}
+	m.viewport = 0
+	return m.loadCmd(path)
+}
+
+// applyPending scrolls to the pending position, if any.
+func (m *SingleFileModel) applyPending() {
+	if m.pending == nil {
+		return
+	}
+	if m.pending.anchor == "" {
+		m.scrollTo(m.pending.viewport)
+		return
+	}
+	if line, ok := anchorLine(m.content, m.lines, m.raw, m.pending.anchor); ok {
+		m.scrollTo(line)
+	} else {
+		m.message = "Anchor not found: #" + m.pending.anchor
+	}
+}
+
+// watchPath returns the file to watch for changes, or "" while nothing
+// watchable is shown.
+func (m *SingleFileModel) watchPath() string {
+	if !m.contentLoaded || m.filepath == m.preloadedPath {
+		return ""
+	}
+	return m.filepath
+}
+
+// reload replaces the document with content that changed on disk, keeping the
+// scroll position. A view scrolled to the end stays at the end.
+func (m *SingleFileModel) reload(content string) tea.Cmd {
+	viewport := m.viewport
+	if m.follow || (viewport > 0 && viewport >= m.bottom()) {
+		viewport = math.MaxInt
+	}
+
+	m.content = content
+	m.messageID++
+	m.message = "Reloaded"
+	m.pending = &pendingPosition{viewport: viewport}
+	hide := clearStatusAfter(m.messageID)
+
+	if m.raw || m.renderer == nil {
+		m.lines = strings.Split(m.content, "\n")
+		m.search.refresh(m.lines)
+		m.applyPending()
+		if m.raw {
+			m.pending = nil
+		}
+		return hide
+	}
+	return tea.Batch(hide, m.renderContent())
+}
+
+// appendStream adds newly arrived input to the document and keeps reading.
+// Input that arrives while another document is shown is only buffered. Raw
+// views update immediately; rendered views are re-rendered at most every
+// streamRenderInterval.
+func (m *SingleFileModel) appendStream(msg streamChunkMsg) tea.Cmd {
+	var cmds []tea.Cmd
+	if msg.done {
+		m.streaming = false
+	} else {
+		cmds = append(cmds, waitForChunk(m.stream))
+	}
+	if msg.err != nil {
+		m.message = fmt.Sprintf("Error reading %s: %v", m.filepath, msg.err)
+	}
+	if msg.data == "" {
+		m.followEnd() // The status line may have gone
+		return tea.Batch(cmds...)
+	}
+
+	// Input keeps arriving while a followed link is shown
+	m.preloaded += msg.data
+	if m.filepath != m.preloadedPath {
+		return tea.Batch(cmds...)
+	}
+	m.content = m.preloaded
+
+	if m.raw {
+		m.lines = strings.Split(m.content, "\n")
+		m.search.refresh(m.lines)
+		m.followEnd()
+	} else {
+		cmds = append(cmds, m.scheduleStreamRender())
+	}
+	return tea.Batch(cmds...)
+}
+
+// scheduleStreamRender re-renders streamed input after streamRenderInterval,
+// unless a render is already pending.
+func (m *SingleFileModel) scheduleStreamRender() tea.Cmd {
+	if m.renderScheduled {
+		return nil
+	}
+	m.renderScheduled = true
+	return tea.Tick(streamRenderInterval, func(t time.Time) tea.Msg {
+		return streamRenderMsg{}
+	})
+}
+
+// bottom returns the viewport that shows the end of the document.
+func (m *SingleFileModel) bottom() int {
+	return max(0, len(m.lines)-m.contentHeight())
+}
+
+// followEnd scrolls to the end of the document in follow mode.
+func (m *SingleFileModel) followEnd() {
+	if m.follow {
+		m.viewport = m.bottom()
+	}
+}
+
+// quit stops reading input and ends the program, remembering the reading
+// position.
+func (m *SingleFileModel) quit() tea.Cmd {
+	if m.stopStream != nil {
+		m.stopStream()
+	}
+	m.rememberPosition()
+	return tea.Quit
+}
+
+// rememberPosition records the reading position of the open file so it can
+// be restored in a later session. Content passed in directly isn't a file and
+// is not remembered.
+func (m *SingleFileModel) rememberPosition() {
+	if m.contentLoaded && m.filepath != m.preloadedPath {
+		rememberFileState(m.filepath, m.content, m.viewport, m.raw)
+	}
+}
+
+// statusLine returns the text of the bottom status line, or "" if it should
+// be hidden.
+func (m *SingleFileModel) statusLine() string {
+	if status := m.search.status(); status != "" {
+		return status
+	}
+	if m.message == "" && m.follow && m.streaming {
+		return "Waiting for data... (scroll up to stop following)"
+	}
+	return m.message
+}
+
+// contentHeight returns the number of lines available for the document,
+// leaving room for the status line when it is shown.
+func (m *SingleFileModel) contentHeight() int {
+	if m.statusLine() != "" {
+		return max(1, m.height-1)
+	}
+	return m.height
+}
+
+// scrollTo moves the viewport so line is at the top of the screen, clamped to
+// the scrollable range.
+func (m *SingleFileModel) scrollTo(line int) {
+	m.viewport = max(0, min(line, len(m.lines)-m.contentHeight()))
+}
+
+// updateSearchInput feeds a key press to the open search prompt and moves to
+// the first match as the query is typed.
+func (m *SingleFileModel) updateSearchInput(msg tea.KeyMsg) {
+	switch m.search.handleInput(msg) {
+	case searchInputEdited:
+		m.search.refresh(m.lines)
+		if line, ok := m.search.jumpFrom(m.search.origin, m.search.backward); ok {
+			m.scrollTo(line)
+		} else {
+			m.viewport = m.search.origin
+		}
+	case searchInputConfirmed:
+		m.search.refresh(m.lines)
+		if line, ok := m.search.jumpFrom(m.search.origin, m.search.backward); ok {
+			m.scrollTo(line)
+		}
+	case searchInputCancelled:
+		m.viewport = m.search.origin
+		m.search.refresh(m.lines)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func TestSingleFileModelCreation(t *testing.T) {
@@ -150,7 +152,7 @@ func TestSingleFileRawToggle(t *testing.T) {
 	// Toggle to raw mode
 	model.raw = true
 	// Simulate async rendering - in raw mode, it should render immediately
-	msg := contentRenderedMsg{lines: strings.Split(model.content, "\n"), err: nil}
+	msg := contentRenderedMsg{lines: strings.Split(model.content, "\n"), source: model.content, settings: model.renderSettings()}
 	updatedModel, _ := model.Update(msg)
 	model = updatedModel.(*SingleFileModel)
 
@@ -229,7 +231,7 @@ func TestSingleFileContentRefresh(t *testing.T) {
 	// Test raw mode refresh
 	model.raw = true
 	// Simulate async rendering for raw mode
-	msg := contentRenderedMsg{lines: strings.Split(model.content, "\n"), err: nil}
+	msg := contentRenderedMsg{lines: strings.Split(model.content, "\n"), source: model.content, settings: model.renderSettings()}
 	updatedModel, _ := model.Update(msg)
 	model = updatedModel.(*SingleFileModel)
 
@@ -312,3 +314,75 @@ func TestSingleFileErrorHandling(t *testing.T) {
 		t.Error("Should show error message")
 	}
 }
+
+func TestSingleFileCycleStyle(t *testing.T) {
+	model, _ := NewSingleFileModelWithContent("notes.md", "# Notes\n")
+	model.width, model.height = 80, 10
+	model.style = "dark"
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	if model.style != "light" || model.message != "Style: light" || cmd == nil {
+		t.Fatalf("Expected t to switch to light, got %s (%q)", model.style, model.message)
+	}
+
+	// A renderer created for a style that is no longer selected is dropped
+	dark, _ := cachedRenderer("dark", 80)
+	model.Update(rendererCreatedMsg{renderer: dark, style: "dark"})
+	if model.renderer != nil {
+		t.Error("Expected the stale renderer to be ignored")
+	}
+	light, _ := cachedRenderer("light", 80)
+	model.Update(rendererCreatedMsg{renderer: light, style: "light"})
+	if model.renderer != light {
+		t.Error("Expected the renderer for the new style to be used")
+	}
+}
+
+func TestSingleFileRawWhileStyleChanges(t *testing.T) {
+	model, _ := NewSingleFileModelWithContent("notes.md", "# Notes\n\nText\n")
+	model.width, model.height = 80, 10
+	model.Update(model.Init()())
+	model.lines = []string{"rendered"}
+	model.renderer, _ = cachedRenderer(model.style, 80)
+
+	// r while the renderer for the next style is still being created
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if !model.raw || strings.Join(model.lines, "\n") != model.content {
+		t.Errorf("Expected the raw source, got %q", model.lines)
+	}
+}
+
+func TestSingleFileDropsRendersForOldSettings(t *testing.T) {
+	model, _ := NewSingleFileModelWithContent("notes.md", "---\ntitle: Notes\n---\n# Notes\n")
+	model.width, model.height = 80, 10
+	model.style = "dark"
+	model.lines = []string{"rendered"}
+	stale := func(settings renderSettings) contentRenderedMsg {
+		return contentRenderedMsg{lines: []string{"stale"}, source: model.content, settings: settings}
+	}
+	rendered := model.renderSettings()
+
+	// A render started before r finishes after it
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	model.Update(stale(rendered))
+	if strings.Join(model.lines, "\n") != model.content {
+		t.Errorf("Expected the raw source to stay, got %q", model.lines)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+
+	// Renders for an earlier style or front matter setting
+	model.lines = []string{"rendered"}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	model.Update(stale(rendered))
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	model.Update(stale(renderSettings{style: model.style, meta: true}))
+	if model.lines[0] != "rendered" {
+		t.Errorf("Expected renders for old settings to be dropped, got %q", model.lines)
+	}
+
+	model.Update(stale(model.renderSettings()))
+	if model.lines[0] != "stale" {
+		t.Errorf("Expected a render for the current settings to be shown, got %q", model.lines)
+	}
+}
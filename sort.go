@@ -0,0 +1,228 @@
+package main
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sortMode is the key the tree is ordered by.
+type sortMode int
+
+const (
+	sortNatural  sortMode = iota // Name, with runs of digits compared as numbers
+	sortName                     // Name, character by character
+	sortModified                 // Modification time, newest first
+	sortSize                     // File size, largest first
+	sortTitle                    // First heading of the document
+	sortModeCount
+)
+
+var sortModeNames = [...]string{"natural", "name", "modified", "size", "title"}
+
+func (s sortMode) String() string {
+	return sortModeNames[s]
+}
+
+// treeSort is the order of the tree's entries within each directory.
+type treeSort struct {
+	mode    sortMode
+	reverse bool
+	mixed   bool // Sort directories among files instead of before them
+}
+
+func (s treeSort) String() string {
+	text := s.mode.String()
+	if s.reverse {
+		text += ", reversed"
+	}
+	if s.mixed {
+		text += ", directories mixed in"
+	}
+	return text
+}
+
+// needsInfo reports whether the order depends on fileInfo.
+func (s treeSort) needsInfo() bool {
+	return s.mode == sortModified || s.mode == sortSize || s.mode == sortTitle
+}
+
+// fileInfo holds what the tree is sorted by besides names. Directories get
+// the newest modification time and the total size of their files.
+type fileInfo struct {
+	modTime time.Time
+	size    int64
+	title   string
+	titled  bool // title has been read
+}
+
+// fileInfoMsg delivers fileInfo read in the background.
+type fileInfoMsg struct {
+	info map[string]fileInfo
+}
+
+// readFileInfo stats paths in fsys, and reads their titles too if withTitles
+// is set.
+func readFileInfo(fsys treeFS, paths []string, withTitles bool) tea.Cmd {
+	return func() tea.Msg {
+		info := make(map[string]fileInfo, len(paths))
+		for _, path := range paths {
+			stat, err := fsys.Stat(path)
+			if err != nil {
+				info[path] = fileInfo{titled: true}
+				continue
+			}
+
+			entry := fileInfo{modTime: stat.ModTime(), size: stat.Size()}
+			if withTitles {
+				entry.title, entry.titled = documentTitle(fsys, path), true
+			}
+			info[path] = entry
+		}
+		return fileInfoMsg{info: info}
+	}
+}
+
+// documentTitle returns the title of a file's front matter or the text of
+// its first heading, or "".
+func documentTitle(fsys treeFS, path string) string {
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if fm, _, ok := splitFrontMatter(string(content)); ok && fm.get("title") != "" {
+		return fm.get("title")
+	}
+	if headings := ParseHeadings(string(content)); len(headings) > 0 {
+		return headings[0].Text
+	}
+	return ""
+}
+
+// sortedTree returns a copy of the tree with every directory's entries in
+// the given order. info supplies modification times, sizes and titles;
+// entries without it sort as zero values. File nodes are shared with the
+// original.
+func sortedTree(node *FileNode, order treeSort, info map[string]fileInfo) *FileNode {
+	if node == nil {
+		return nil
+	}
+	sorted, _ := sortNode(node, order, info)
+	return sorted
+}
+
+func sortNode(node *FileNode, order treeSort, info map[string]fileInfo) (*FileNode, fileInfo) {
+	if !node.IsDir {
+		return node, info[node.Path]
+	}
+
+	copied := *node
+	copied.Children = make([]*FileNode, len(node.Children))
+	keys := make(map[*FileNode]fileInfo, len(node.Children))
+
+	var total fileInfo
+	for i, child := range node.Children {
+		sorted, key := sortNode(child, order, info)
+		copied.Children[i] = sorted
+		keys[sorted] = key
+
+		if key.modTime.After(total.modTime) {
+			total.modTime = key.modTime
+		}
+		total.size += key.size
+	}
+
+	sort.SliceStable(copied.Children, func(i, j int) bool {
+		a, b := copied.Children[i], copied.Children[j]
+		if !order.mixed && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if c := compareNodes(a, b, keys[a], keys[b], order.mode); c != 0 {
+			return (c < 0) != order.reverse
+		}
+		return false
+	})
+
+	return &copied, total
+}
+
+// compareNodes orders two entries by mode, falling back to their names.
+func compareNodes(a, b *FileNode, aInfo, bInfo fileInfo, mode sortMode) int {
+	switch mode {
+	case sortModified:
+		// Newest first
+		if c := bInfo.modTime.Compare(aInfo.modTime); c != 0 {
+			return c
+		}
+	case sortSize:
+		// Largest first
+		if c := cmp.Compare(bInfo.size, aInfo.size); c != 0 {
+			return c
+		}
+	case sortTitle:
+		if c := naturalCompare(nodeTitle(a, aInfo), nodeTitle(b, bInfo)); c != 0 {
+			return c
+		}
+	case sortName:
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	}
+	return naturalCompare(a.Name, b.Name)
+}
+
+// nodeTitle is the text a node is sorted by in title order: its first
+// heading, or its name.
+func nodeTitle(node *FileNode, info fileInfo) string {
+	if info.title != "" {
+		return info.title
+	}
+	return node.Name
+}
+
+// naturalCompare compares strings case-insensitively, treating runs of
+// digits as numbers so that "2-setup" comes before "10-deploy".
+func naturalCompare(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			aNum, aRest := splitDigits(a)
+			bNum, bRest := splitDigits(b)
+
+			// Compare the numbers by length without leading zeros, then
+			// digit by digit, so any length of number works
+			aTrim, bTrim := strings.TrimLeft(aNum, "0"), strings.TrimLeft(bNum, "0")
+			if len(aTrim) != len(bTrim) {
+				return cmp.Compare(len(aTrim), len(bTrim))
+			}
+			if c := strings.Compare(aTrim, bTrim); c != 0 {
+				return c
+			}
+			if len(aNum) != len(bNum) {
+				return cmp.Compare(len(aNum), len(bNum))
+			}
+			a, b = aRest, bRest
+			continue
+		}
+
+		if a[0] != b[0] {
+			return cmp.Compare(int(a[0]), int(b[0]))
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// splitDigits splits the leading run of digits off s.
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
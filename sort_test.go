@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"2-setup.md", "10-deploy.md", -1},
+		{"adr-0009.md", "adr-0010.md", -1},
+		{"ADR-9.md", "adr-10.md", -1},
+		{"file.md", "File.md", 0},
+		{"v1.2.md", "v1.10.md", -1},
+		{"07.md", "7.md", 1}, // Leading zeros sort after the same number
+		{"a.md", "a1.md", -1},
+		{"chapter", "2-chapter", 1},
+		{"99999999999999999999.md", "100000000000000000000.md", -1},
+	}
+
+	for _, test := range tests {
+		if got := naturalCompare(test.a, test.b); got != test.expected {
+			t.Errorf("naturalCompare(%q, %q) = %d, expected %d", test.a, test.b, got, test.expected)
+		}
+		if got := naturalCompare(test.b, test.a); got != -test.expected {
+			t.Errorf("naturalCompare(%q, %q) = %d, expected %d", test.b, test.a, got, -test.expected)
+		}
+	}
+}
+
+func TestSortedTree(t *testing.T) {
+	root := &FileNode{
+		Name:  "root",
+		IsDir: true,
+		Children: []*FileNode{
+			{Name: "adr", Path: "/adr", IsDir: true, Children: []*FileNode{
+				{Name: "10-deploy.md", Path: "/adr/10-deploy.md"},
+				{Name: "2-setup.md", Path: "/adr/2-setup.md"},
+			}},
+			{Name: "b.md", Path: "/b.md"},
+			{Name: "a.md", Path: "/a.md"},
+		},
+	}
+	now := time.Now()
+	info := map[string]fileInfo{
+		"/adr/10-deploy.md": {modTime: now.Add(-3 * time.Hour), size: 10, title: "Deploy"},
+		"/adr/2-setup.md":   {modTime: now.Add(-2 * time.Hour), size: 20, title: "Setup"},
+		"/b.md":             {modTime: now, size: 5, title: "Alpha"},
+		"/a.md":             {modTime: now.Add(-time.Hour), size: 50, title: "Beta"},
+	}
+
+	names := func(node *FileNode) []string {
+		var names []string
+		for _, child := range node.Children {
+			names = append(names, child.Name)
+		}
+		return names
+	}
+	tests := []struct {
+		order    treeSort
+		expected []string
+	}{
+		{treeSort{mode: sortNatural}, []string{"adr", "a.md", "b.md"}},
+		{treeSort{mode: sortNatural, reverse: true}, []string{"adr", "b.md", "a.md"}},
+		{treeSort{mode: sortModified}, []string{"adr", "b.md", "a.md"}},
+		{treeSort{mode: sortModified, mixed: true}, []string{"b.md", "a.md", "adr"}},
+		{treeSort{mode: sortSize, mixed: true}, []string{"a.md", "adr", "b.md"}},
+		{treeSort{mode: sortTitle}, []string{"adr", "b.md", "a.md"}},
+		{treeSort{mode: sortTitle, mixed: true}, []string{"adr", "b.md", "a.md"}},
+	}
+
+	for _, test := range tests {
+		got := names(sortedTree(root, test.order, info))
+		if len(got) != len(test.expected) {
+			t.Fatalf("%s: expected %v, got %v", test.order, test.expected, got)
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.order, test.expected, got)
+				break
+			}
+		}
+	}
+
+	// Numbered files are in numeric order, and the original is untouched
+	if adr := sortedTree(root, treeSort{}, info).Children[0]; adr.Children[0].Name != "2-setup.md" {
+		t.Errorf("Expected 2-setup.md first, got %v", names(adr))
+	}
+	if root.Children[1].Name != "b.md" || root.Children[0].Children[0].Name != "10-deploy.md" {
+		t.Error("Expected the original tree to be unchanged")
+	}
+}
+
+func TestDualPaneSortModes(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		"10-deploy.md": "# Deploy",
+		"2-setup.md":   "# Setup",
+		"large.md":     "# Another\n\n" + string(make([]byte, 1000)),
+	})
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(tempDir, "large.md"), old, old); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	m, err := NewDualPaneModelWithRoots([]string{tempDir}, FinderOptions{})
+	if err != nil {
+		t.Fatalf("NewDualPaneModelWithRoots failed: %v", err)
+	}
+	m.raw = true
+	runScan(t, m)
+	finishWalk(t, m, m.walk)
+
+	order := func() string {
+		var names string
+		for _, row := range m.treeRows {
+			names += row.node.Name + " "
+		}
+		return names
+	}
+	press := func(key string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		// Deliver the file info the new order needs
+		if m.readingInfo {
+			m.Update(readFileInfo(m.fsys, m.allFiles, m.sortOrder.mode == sortTitle)())
+		}
+	}
+
+	if got := order(); got != "2-setup.md 10-deploy.md large.md " {
+		t.Errorf("Expected natural order by default, got %s", got)
+	}
+	press("s") // Name
+	if got := order(); got != "10-deploy.md 2-setup.md large.md " {
+		t.Errorf("Expected name order, got %s", got)
+	}
+	press("s") // Modified
+	if got := order(); got != "10-deploy.md 2-setup.md large.md " && got != "2-setup.md 10-deploy.md large.md " {
+		t.Errorf("Expected large.md last by modification time, got %s", got)
+	}
+	press("s") // Size
+	if got := order(); got[:9] != "large.md " {
+		t.Errorf("Expected large.md first by size, got %s", got)
+	}
+	press("s") // Title
+	if got := order(); got != "large.md 10-deploy.md 2-setup.md " {
+		t.Errorf("Expected title order, got %s", got)
+	}
+	press("S")
+	if got := order(); got != "2-setup.md 10-deploy.md large.md " {
+		t.Errorf("Expected reversed title order, got %s", got)
+	}
+	if m.statusMessage != "Sort: title, reversed" {
+		t.Errorf("Expected the order in the status bar, got %q", m.statusMessage)
+	}
+}
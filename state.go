@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRememberedFiles bounds the state file; the least recently viewed
+// entries are dropped first.
+const maxRememberedFiles = 1000
+
+// fileState is the remembered reading position of a file. It is only
+// restored while the file's content hash still matches.
+type fileState struct {
+	Hash     string    `json:"hash"`
+	Viewport int       `json:"viewport"`
+	Raw      bool      `json:"raw"`
+	Updated  time.Time `json:"updated"`
+}
+
+// treeState is the remembered dual pane layout for a tree root.
+type treeState struct {
+	LastFile   string    `json:"last_file"`
+	SplitRatio float64   `json:"split_ratio"`
+	Updated    time.Time `json:"updated"`
+}
+
+// viewerState is the content of the state file, keyed by absolute path.
+type viewerState struct {
+	Files map[string]fileState `json:"files"`
+	Trees map[string]treeState `json:"trees"`
+}
+
+var (
+	stateMutex  sync.Mutex
+	stateCache  *viewerState
+	stateLoaded string // Path the cache was loaded from
+	stateDirty  bool
+)
+
+// stateFilePath returns where reading positions are stored:
+// $XDG_STATE_HOME/md/state.json, defaulting to ~/.local/state.
+func stateFilePath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "md", "state.json")
+}
+
+func readViewerState(path string) *viewerState {
+	state := &viewerState{}
+	if data, err := os.ReadFile(path); err == nil {
+		// A corrupt state file is treated as empty rather than an error
+		_ = json.Unmarshal(data, state)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]fileState)
+	}
+	if state.Trees == nil {
+		state.Trees = make(map[string]treeState)
+	}
+	return state
+}
+
+// cachedState returns the in-memory state, loading it on first use. Callers
+// must hold stateMutex.
+func cachedState() *viewerState {
+	path := stateFilePath()
+	if stateCache == nil || stateLoaded != path {
+		stateCache = readViewerState(path)
+		stateLoaded = path
+		stateDirty = false
+	}
+	return stateCache
+}
+
+// contentHash identifies a version of a file's content.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:16])
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func allAbsPaths(paths []string) []string {
+	abs := make([]string, len(paths))
+	for i, path := range paths {
+		abs[i] = absPath(path)
+	}
+	return abs
+}
+
+// lookupFileState returns the remembered position of path, provided its
+// content hasn't changed since.
+func lookupFileState(path, content string) (fileState, bool) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	state, ok := cachedState().Files[absPath(path)]
+	if !ok || state.Hash != contentHash(content) {
+		return fileState{}, false
+	}
+	return state, true
+}
+
+// rememberFileState records the position of path in memory; it is written
+// out by saveViewerState.
+func rememberFileState(path, content string, viewport int, raw bool) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	cachedState().Files[absPath(path)] = fileState{
+		Hash:     contentHash(content),
+		Viewport: viewport,
+		Raw:      raw,
+		Updated:  time.Now(),
+	}
+	stateDirty = true
+}
+
+// lookupTreeState returns the remembered layout for a tree root.
+func lookupTreeState(root string) (treeState, bool) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	state, ok := cachedState().Trees[absPath(root)]
+	return state, ok
+}
+
+// rememberTreeState records the layout for a tree root in memory.
+func rememberTreeState(root, lastFile string, splitRatio float64) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if lastFile != "" {
+		lastFile = absPath(lastFile)
+	}
+	cachedState().Trees[absPath(root)] = treeState{
+		LastFile:   lastFile,
+		SplitRatio: splitRatio,
+		Updated:    time.Now(),
+	}
+	stateDirty = true
+}
+
+// saveViewerState writes remembered positions to the state file. Entries
+// written by other sessions in the meantime are kept unless this session has
+// newer ones.
+func saveViewerState() error {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if stateCache == nil || !stateDirty {
+		return nil
+	}
+	path := stateFilePath()
+	if path == "" {
+		return nil
+	}
+
+	merged := readViewerState(path)
+	for key, state := range stateCache.Files {
+		if existing, ok := merged.Files[key]; !ok || state.Updated.After(existing.Updated) {
+			merged.Files[key] = state
+		}
+	}
+	for key, state := range stateCache.Trees {
+		if existing, ok := merged.Trees[key]; !ok || state.Updated.After(existing.Updated) {
+			merged.Trees[key] = state
+		}
+	}
+	pruneFileStates(merged.Files, maxRememberedFiles)
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write atomically so a concurrent reader never sees a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	stateCache = merged
+	stateDirty = false
+	return nil
+}
+
+// pruneFileStates drops the oldest entries beyond limit.
+func pruneFileStates(files map[string]fileState, limit int) {
+	if len(files) <= limit {
+		return
+	}
+
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return files[keys[i]].Updated.After(files[keys[j]].Updated)
+	})
+	for _, key := range keys[limit:] {
+		delete(files, key)
+	}
+}
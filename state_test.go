@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestViewerStateRoundTrip(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	rememberFileState("notes.md", "# Notes\n", 12, true)
+	rememberTreeState(".", "notes.md", 0.4)
+	if err := saveViewerState(); err != nil {
+		t.Fatalf("saveViewerState failed: %v", err)
+	}
+
+	// Drop the in-memory copy so the state is read back from disk
+	stateMutex.Lock()
+	stateCache = nil
+	stateMutex.Unlock()
+
+	state, ok := lookupFileState("notes.md", "# Notes\n")
+	if !ok || state.Viewport != 12 || !state.Raw {
+		t.Errorf("Expected viewport 12 in raw mode, got %+v (%t)", state, ok)
+	}
+
+	if _, ok := lookupFileState("notes.md", "# Notes\nEdited\n"); ok {
+		t.Error("Expected no position for changed content")
+	}
+
+	tree, ok := lookupTreeState(".")
+	if !ok || tree.SplitRatio != 0.4 || tree.LastFile != absPath("notes.md") {
+		t.Errorf("Expected tree state for notes.md at 0.4, got %+v (%t)", tree, ok)
+	}
+}
+
+func TestPruneFileStates(t *testing.T) {
+	now := time.Now()
+	files := map[string]fileState{
+		"old.md":    {Updated: now.Add(-time.Hour)},
+		"recent.md": {Updated: now},
+		"middle.md": {Updated: now.Add(-time.Minute)},
+	}
+
+	pruneFileStates(files, 2)
+
+	if _, ok := files["old.md"]; ok || len(files) != 2 {
+		t.Errorf("Expected the oldest entry to be dropped, got %v", files)
+	}
+}
+
+func TestSingleFileRestoresPosition(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	tempDir, err := os.MkdirTemp("", "md_test_state")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "long.md")
+	content := strings.Repeat("line\n", 100)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	model, _ := NewSingleFileModel(path)
+	model.raw = true
+	model.height = 10
+	model.Update(model.Init()())
+	model.scrollTo(40)
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+
+	// A new session resumes at the same line, in raw mode
+	reopened, _ := NewSingleFileModel(path)
+	reopened.height = 10
+	reopened.Update(reopened.Init()())
+	if !reopened.raw || reopened.viewport != 40 {
+		t.Errorf("Expected raw view at line 40, got raw=%t viewport=%d", reopened.raw, reopened.viewport)
+	}
+
+	// Once the file changes the remembered position no longer applies
+	if err := os.WriteFile(path, []byte(content+"more\n"), 0644); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+	changed, _ := NewSingleFileModel(path)
+	changed.raw = true
+	changed.height = 10
+	changed.Update(changed.Init()())
+	if changed.viewport != 0 {
+		t.Errorf("Expected changed file to start at the top, got %d", changed.viewport)
+	}
+}
+
+func TestDualPaneRestoresSelection(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir, paths := writeSearchFixture(t)
+	c := paths[2]
+
+	tree, err := FindMarkdownFiles(tempDir, false)
+	if err != nil {
+		t.Fatalf("FindMarkdownFiles failed: %v", err)
+	}
+	rememberTreeState(tempDir, c, 0.45)
+
+	m := &DualPaneModel{
+		rootPath:   tempDir,
+		width:      100,
+		height:     12,
+		splitRatio: 0.3,
+		raw:        true,
+	}
+	m.Update(loadCompleteMsg{tree: tree})
+
+	if m.currentFile() != c {
+		t.Errorf("Expected remembered file %s to be selected, got %s", c, m.currentFile())
+	}
+	if m.splitRatio != 0.45 {
+		t.Errorf("Expected split ratio 0.45, got %v", m.splitRatio)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// streamChunkSize is the size of a single read from a stream.
+	streamChunkSize = 32 * 1024
+	// streamRenderInterval throttles re-rendering while input is arriving, so
+	// a fast producer doesn't keep the renderer permanently busy.
+	streamRenderInterval = 100 * time.Millisecond
+)
+
+// streamChunk is a piece of input read from a stream.
+type streamChunk struct {
+	data string
+	err  error
+}
+
+// streamChunkMsg delivers the input that arrived since the last message.
+// done is set once the stream has ended, err if reading it failed.
+type streamChunkMsg struct {
+	data string
+	err  error
+	done bool
+}
+
+// streamRenderMsg triggers a throttled re-render of streamed content.
+type streamRenderMsg struct{}
+
+// readStream reads r in the background until EOF, an error or until ctx is
+// done. A reader that can be closed is closed when ctx is done, so that a
+// blocked read returns. The channel is closed when reading stops.
+func readStream(ctx context.Context, r io.Reader) <-chan streamChunk {
+	chunks := make(chan streamChunk, 64)
+	if closer, ok := r.(io.Closer); ok {
+		context.AfterFunc(ctx, func() { closer.Close() })
+	}
+
+	send := func(chunk streamChunk) bool {
+		select {
+		case chunks <- chunk:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, streamChunkSize)
+		for {
+			n, err := r.Read(buf)
+			if ctx.Err() != nil {
+				return // Whatever the read returned, nobody is waiting for it
+			}
+			if n > 0 && !send(streamChunk{data: string(buf[:n])}) {
+				return
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				send(streamChunk{err: err})
+				return
+			}
+		}
+	}()
+	return chunks
+}
+
+// waitForChunk waits for the next input from the stream. Chunks that are
+// already buffered are combined into one message so the model isn't updated
+// once per read.
+func waitForChunk(chunks <-chan streamChunk) tea.Cmd {
+	return func() tea.Msg {
+		chunk, ok := <-chunks
+		if !ok {
+			return streamChunkMsg{done: true}
+		}
+
+		var data strings.Builder
+		data.WriteString(chunk.data)
+		for chunk.err == nil {
+			select {
+			case chunk, ok = <-chunks:
+				if !ok {
+					return streamChunkMsg{data: data.String(), done: true}
+				}
+				data.WriteString(chunk.data)
+			default:
+				return streamChunkMsg{data: data.String()}
+			}
+		}
+		return streamChunkMsg{data: data.String(), err: chunk.err, done: true}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestWaitForChunk(t *testing.T) {
+	reader, writer := io.Pipe()
+	chunks := readStream(context.Background(), reader)
+
+	go writer.Write([]byte("# Title\n"))
+	msg := waitForChunk(chunks)().(streamChunkMsg)
+	if msg.data != "# Title\n" || msg.done {
+		t.Errorf("Expected first chunk, got %+v", msg)
+	}
+
+	writer.CloseWithError(errors.New("broken pipe"))
+	msg = waitForChunk(chunks)().(streamChunkMsg)
+	if msg.err == nil || !msg.done {
+		t.Errorf("Expected read error to end the stream, got %+v", msg)
+	}
+
+	msg = waitForChunk(chunks)().(streamChunkMsg)
+	if !msg.done {
+		t.Errorf("Expected closed stream to report done, got %+v", msg)
+	}
+}
+
+func TestSingleFileFollowsStream(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	model, _ := NewSingleFileModelWithStream("stdin", reader)
+	model.raw = true
+	model.height = 10
+
+	send := func(data string) {
+		go writer.Write([]byte(data))
+		_, cmd := model.Update(model.Init()())
+		if cmd == nil {
+			t.Fatal("Expected to keep reading the stream")
+		}
+	}
+
+	send(strings.Repeat("line\n", 30))
+	if model.viewport != model.bottom() || model.viewport == 0 {
+		t.Errorf("Expected view pinned to the end, got viewport %d", model.viewport)
+	}
+	if !strings.HasPrefix(model.statusLine(), "Waiting for data") {
+		t.Errorf("Expected follow indicator, got %q", model.statusLine())
+	}
+
+	// Scrolling up stops following
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	viewport := model.viewport
+	send(strings.Repeat("more\n", 10))
+	if model.follow || model.viewport != viewport {
+		t.Errorf("Expected view to stay at %d after scrolling up, got %d", viewport, model.viewport)
+	}
+
+	// F resumes following
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'F'}})
+	if !model.follow || model.viewport != model.bottom() {
+		t.Errorf("Expected F to jump to the end, got viewport %d of %d", model.viewport, model.bottom())
+	}
+	send("last\n")
+	if model.lines[len(model.lines)-2] != "last" || model.viewport != model.bottom() {
+		t.Errorf("Expected new input at the end to stay visible, got viewport %d", model.viewport)
+	}
+}
+
+func TestSingleFileStreamWhileFollowingLink(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{"other.md": "# Other\n"})
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	stdin := filepath.Join(tempDir, "stdin")
+	model, _ := NewSingleFileModelWithStream(stdin, reader)
+	model.raw = true
+	model.height = 10
+	model.width = 80
+
+	send := func(data string) {
+		go writer.Write([]byte(data))
+		model.Update(waitForChunk(model.stream)())
+	}
+
+	send("see [o](other.md)\n")
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(cmd())
+	if model.content != "# Other\n" {
+		t.Fatalf("Expected other.md to be open, got %q", model.content)
+	}
+
+	// Input arriving meanwhile is kept for stdin only
+	send("more stdin\n")
+	if model.content != "# Other\n" || model.lines[0] != "# Other" {
+		t.Errorf("Expected other.md to stay as it is, got %q", model.content)
+	}
+
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'['}})
+	model.Update(cmd())
+	if model.filepath != stdin || model.content != "see [o](other.md)\nmore stdin\n" {
+		t.Errorf("Expected back to show all of stdin, got %s: %q", model.filepath, model.content)
+	}
+}
+
+func TestSingleFileQuitStopsStream(t *testing.T) {
+	reader, writer := io.Pipe()
+	model, _ := NewSingleFileModelWithStream("stdin", reader)
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	go writer.Write([]byte("late"))
+	if msg := waitForChunk(model.stream)().(streamChunkMsg); !msg.done || msg.data != "" || msg.err != nil {
+		t.Errorf("Expected the stream to end without more input, got %+v", msg)
+	}
+}
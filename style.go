@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// builtinStyles are glamour's standard styles, in the order t cycles through
+// them.
+var builtinStyles = []string{"dark", "light", "dracula", "tokyo-night", "notty", "ascii"}
+
+// renderStyle is the style documents are rendered in when a model starts:
+// one of builtinStyles, or the path of a glamour JSON stylesheet.
+var renderStyle = "dark"
+
+// setRenderStyle sets the starting style. An empty style or "auto" picks
+// one for the terminal. The style is loaded once up front so a mistake in it
+// is reported before the UI starts.
+func setRenderStyle(style string) error {
+	if style == "" || style == "auto" {
+		style = autoStyle()
+	}
+	if !slices.Contains(builtinStyles, style) {
+		if _, err := os.Stat(style); err != nil {
+			return fmt.Errorf("unknown style %q: expected auto, one of %s or a JSON stylesheet", style, strings.Join(builtinStyles, ", "))
+		}
+	}
+	if _, err := glamour.NewTermRenderer(glamour.WithStylePath(style)); err != nil {
+		return fmt.Errorf("style %s: %v", style, err)
+	}
+	renderStyle = style
+	return nil
+}
+
+// nextStyle returns the style after current: the builtin styles in order,
+// followed by the custom stylesheet if one was given.
+func nextStyle(current string) string {
+	styles := builtinStyles
+	if !slices.Contains(styles, renderStyle) {
+		styles = append(slices.Clone(styles), renderStyle)
+	}
+	i := slices.Index(styles, current)
+	return styles[(i+1)%len(styles)]
+}
+
+// styleName is how a style is shown to the user: its name, or the file name
+// of a custom stylesheet.
+func styleName(style string) string {
+	if slices.Contains(builtinStyles, style) {
+		return style
+	}
+	return filepath.Base(style)
+}
+
+// rendererKey identifies a renderer in the cache.
+type rendererKey struct {
+	style string
+	width int
+	plain bool // Without colors, see noColor
+}
+
+var (
+	rendererCache = make(map[rendererKey]*glamour.TermRenderer)
+	rendererMutex sync.RWMutex
+)
+
+// cachedRenderer returns a renderer for the style that wraps at width,
+// creating it the first time it is asked for. With colors turned off, every
+// style renders as the plain one.
+func cachedRenderer(style string, width int) (*glamour.TermRenderer, error) {
+	key := rendererKey{style: style, width: width, plain: noColor()}
+
+	rendererMutex.RLock()
+	cached, exists := rendererCache[key]
+	rendererMutex.RUnlock()
+	if exists {
+		return cached, nil
+	}
+
+	if key.plain {
+		style = plainStyle()
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStylePath(style),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rendererMutex.Lock()
+	rendererCache[key] = renderer
+	rendererMutex.Unlock()
+	return renderer, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSetRenderStyle(t *testing.T) {
+	defer func(style string) { renderStyle = style }(renderStyle)
+
+	if err := setRenderStyle("dracula"); err != nil || renderStyle != "dracula" {
+		t.Fatalf("Expected dracula, got %s (%v)", renderStyle, err)
+	}
+	if err := setRenderStyle("no-such-style"); err == nil || renderStyle != "dracula" {
+		t.Errorf("Expected an error for an unknown style, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "mine.json")
+	if err := os.WriteFile(path, []byte(`{"document": {"margin": 1}}`), 0644); err != nil {
+		t.Fatalf("Failed to write stylesheet: %v", err)
+	}
+	if err := setRenderStyle(path); err != nil || renderStyle != path {
+		t.Fatalf("Expected the custom stylesheet, got %s (%v)", renderStyle, err)
+	}
+	if styleName(path) != "mine.json" {
+		t.Errorf("Expected the stylesheet to be shown by file name, got %s", styleName(path))
+	}
+
+	// The custom stylesheet comes after the builtin styles
+	if next := nextStyle("ascii"); next != path {
+		t.Errorf("Expected the stylesheet after ascii, got %s", next)
+	}
+	if next := nextStyle(path); next != "dark" {
+		t.Errorf("Expected dark after the stylesheet, got %s", next)
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0644); err != nil {
+		t.Fatalf("Failed to write stylesheet: %v", err)
+	}
+	if err := setRenderStyle(path); err == nil {
+		t.Error("Expected an error for an invalid stylesheet")
+	}
+}
+
+func TestCachedRenderer(t *testing.T) {
+	dark, err := cachedRenderer("dark", 50)
+	if err != nil {
+		t.Fatalf("cachedRenderer failed: %v", err)
+	}
+	if again, _ := cachedRenderer("dark", 50); again != dark {
+		t.Error("Expected the cached renderer to be reused")
+	}
+	if light, _ := cachedRenderer("light", 50); light == dark {
+		t.Error("Expected a separate renderer per style")
+	}
+	if wider, _ := cachedRenderer("dark", 60); wider == dark {
+		t.Error("Expected a separate renderer per width")
+	}
+
+	// NO_COLOR applies whatever the style
+	t.Setenv("NO_COLOR", "1")
+	plain, err := cachedRenderer("dark", 50)
+	if err != nil || plain == dark {
+		t.Fatalf("Expected a separate renderer without colors, got %v", err)
+	}
+	out, err := plain.Render("# Title\n\nSome `code` and **bold** text.\n")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("Expected no escape sequences with NO_COLOR, got %q", out)
+	}
+}
+
+func TestDualPaneCycleStyle(t *testing.T) {
+	m := &DualPaneModel{
+		style:          "dark",
+		width:          100,
+		height:         20,
+		splitRatio:     0.3,
+		currentContent: "# Title\n",
+	}
+	m.updateRendererWidth()
+	dark := m.renderer
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	if m.style != "light" || m.statusMessage != "Style: light" {
+		t.Errorf("Expected t to switch to light, got %s (%q)", m.style, m.statusMessage)
+	}
+	if m.renderer == nil || m.renderer == dark {
+		t.Error("Expected a renderer for the new style")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// glyphSet holds the characters the UI is drawn with.
+type glyphSet struct {
+	ascii         bool
+	treeBranch    string // Tree guides, all four columns wide
+	treeLast      string
+	treePipe      string
+	scrollThumb   string
+	scrollTrack   string
+	cursor        string // Marks the selected row
+	inputCursor   string
+	arrow         string
+	border        lipgloss.Border
+	focusedBorder lipgloss.Border
+}
+
+var unicodeGlyphs = glyphSet{
+	treeBranch:    "├── ",
+	treeLast:      "└── ",
+	treePipe:      "│   ",
+	scrollThumb:   "█",
+	scrollTrack:   "░",
+	cursor:        "❯",
+	inputCursor:   "█",
+	arrow:         "→",
+	border:        lipgloss.NormalBorder(),
+	focusedBorder: lipgloss.RoundedBorder(),
+}
+
+var asciiGlyphs = glyphSet{
+	ascii:       true,
+	treeBranch:  "|-- ",
+	treeLast:    "`-- ",
+	treePipe:    "|   ",
+	scrollThumb: "#",
+	scrollTrack: ":",
+	cursor:      ">",
+	inputCursor: "_",
+	arrow:       "->",
+	border:      lipgloss.ASCIIBorder(),
+	// Without colors the focused pane needs a border of its own
+	focusedBorder: lipgloss.Border{
+		Top: "=", Bottom: "=", Left: "|", Right: "|",
+		TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+	},
+}
+
+// glyphs are the characters in use, picked at startup.
+var glyphs = unicodeGlyphs
+
+// unicodeTerminal reports whether the terminal can display box drawing
+// characters: it isn't a dumb or serial terminal, and the locale, if one is
+// set, uses UTF-8.
+func unicodeTerminal() bool {
+	term := os.Getenv("TERM")
+	if term == "dumb" || strings.HasPrefix(term, "vt") {
+		return false
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := strings.ToLower(os.Getenv(name)); locale != "" {
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return true
+}
+
+// noColor reports whether colors are turned off, by NO_COLOR
+// (https://no-color.org) or a dumb terminal.
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+}
+
+// autoStyle picks the rendering style for the terminal: the plain style if
+// colors are turned off, otherwise the light or dark style matching its
+// background.
+func autoStyle() string {
+	switch {
+	case noColor():
+		return plainStyle()
+	case lipgloss.HasDarkBackground():
+		return "dark"
+	}
+	return "light"
+}
+
+// plainStyle is the style used without colors: ascii if the terminal can't
+// show box drawing either, otherwise notty.
+func plainStyle() string {
+	if glyphs.ascii {
+		return "ascii"
+	}
+	return "notty"
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnicodeTerminal(t *testing.T) {
+	tests := []struct {
+		term, lcAll, lang string
+		expected          bool
+	}{
+		{"xterm-256color", "", "en_US.UTF-8", true},
+		{"xterm-256color", "", "", true},
+		{"xterm-256color", "C", "en_US.UTF-8", false},
+		{"screen", "", "de_DE.utf8", true},
+		{"linux", "", "POSIX", false},
+		{"dumb", "", "en_US.UTF-8", false},
+		{"vt220", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Setenv("TERM", test.term)
+		t.Setenv("LC_ALL", test.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", test.lang)
+		if got := unicodeTerminal(); got != test.expected {
+			t.Errorf("TERM=%s LC_ALL=%s LANG=%s: expected %t, got %t", test.term, test.lcAll, test.lang, test.expected, got)
+		}
+	}
+}
+
+func TestAutoStyle(t *testing.T) {
+	defer func(g glyphSet) { glyphs = g }(glyphs)
+
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("NO_COLOR", "1")
+	if style := autoStyle(); style != "notty" {
+		t.Errorf("Expected notty with NO_COLOR, got %s", style)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "dumb")
+	if !noColor() {
+		t.Error("Expected no colors on a dumb terminal")
+	}
+
+	glyphs = asciiGlyphs
+	if style := autoStyle(); style != "ascii" {
+		t.Errorf("Expected ascii without colors or box drawing, got %s", style)
+	}
+
+	// A terminal without box drawing keeps its colors
+	t.Setenv("TERM", "xterm-256color")
+	if style := autoStyle(); style != "dark" && style != "light" {
+		t.Errorf("Expected a colored style with ASCII glyphs, got %s", style)
+	}
+}
+
+func TestFlattenTreeASCII(t *testing.T) {
+	defer func(g glyphSet) { glyphs = g }(glyphs)
+	glyphs = asciiGlyphs
+
+	root := &FileNode{Name: "root", IsDir: true, Children: []*FileNode{
+		{Name: "docs", IsDir: true, Children: []*FileNode{
+			{Name: "guide.md"},
+			{Name: "setup.md"},
+		}},
+		{Name: "README.md"},
+	}}
+
+	expected := []string{
+		"    |-- [-] docs/",
+		"    |   |-- [-] guide.md",
+		"    |   `-- [-] setup.md",
+		"    `-- [-] README.md",
+	}
+	lines := FlattenTree(root, "", false)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestDualPaneViewASCII(t *testing.T) {
+	defer func(g glyphSet) { glyphs = g }(glyphs)
+	glyphs = asciiGlyphs
+
+	tree := &FileNode{Name: "root", IsDir: true, Children: []*FileNode{
+		{Name: "docs", Path: "docs", IsDir: true, Children: []*FileNode{{Name: "guide.md", Path: "docs/guide.md"}}},
+	}}
+	m := &DualPaneModel{
+		fileTree:       tree,
+		allFiles:       CollectFiles(tree),
+		width:          120,
+		height:         10,
+		splitRatio:     0.4,
+		raw:            true,
+		currentContent: "# Guide\n\nPlain text.\n",
+	}
+	m.rebuildTree()
+	m.refreshContent()
+
+	view := m.View()
+	for _, r := range view {
+		if r > 127 {
+			t.Fatalf("Expected only ASCII characters, found %q in\n%s", r, view)
+		}
+	}
+	if !strings.Contains(view, "`-- [-] guide.md") {
+		t.Errorf("Expected ASCII tree guides in\n%s", view)
+	}
+}
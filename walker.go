@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// walkRebuildInterval throttles rebuilding the tree while a walk is finding
+// files, so that a large tree isn't rebuilt once per directory.
+const walkRebuildInterval = 100 * time.Millisecond
+
+// walkRebuildMsg triggers a throttled rebuild of the tree with the files a
+// walk found since the last one.
+type walkRebuildMsg struct{}
+
+// walkEntry is a file or directory found by a treeWalker.
+type walkEntry struct {
+	path  string
+	isDir bool
+}
+
+// walkBatchMsg delivers the entries found since the last message. id
+// identifies the walk so results of a cancelled one can be dropped; done is
+// set once the walk has finished.
+type walkBatchMsg struct {
+	id      int
+	entries []walkEntry
+	done    bool
+}
+
+// treeWalker discovers markdown files in the background, breadth first, so
+// shallow files show up before deep ones. It has no depth limit.
+type treeWalker struct {
+	id      int
+	cancel  context.CancelFunc
+	batches <-chan []walkEntry
+}
+
+// startWalk walks the directories among roots, which must be absolute, in
+// fsys. Each directory read is sent as one batch of the entries that belong
+// in the tree.
+func startWalk(id int, fsys treeFS, roots []string, opts FinderOptions) *treeWalker {
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []walkEntry, 64)
+
+	go func() {
+		defer close(batches)
+
+		for _, root := range roots {
+			if info, err := fsys.Stat(root); err != nil || !info.IsDir() {
+				continue
+			}
+
+			filter := fsys.entryFilter(root, opts)
+
+			queue := []string{root}
+			for len(queue) > 0 && ctx.Err() == nil {
+				dir := queue[0]
+				queue = queue[1:]
+
+				entries, err := fsys.ReadDir(dir)
+				if err != nil {
+					continue // Unreadable directories are left empty
+				}
+
+				var batch []walkEntry
+				for _, entry := range entries {
+					path := filepath.Join(dir, entry.Name())
+					if !filter.include(path, entry) {
+						continue
+					}
+					batch = append(batch, walkEntry{path: path, isDir: entry.IsDir()})
+					if entry.IsDir() {
+						queue = append(queue, path)
+					}
+				}
+				if len(batch) == 0 {
+					continue
+				}
+
+				select {
+				case batches <- batch:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return &treeWalker{id: id, cancel: cancel, batches: batches}
+}
+
+// next waits for the walker's next results. Batches that are already
+// buffered are combined into one message so the tree isn't rebuilt once per
+// directory.
+func (w *treeWalker) next() tea.Cmd {
+	return func() tea.Msg {
+		batch, ok := <-w.batches
+		if !ok {
+			return walkBatchMsg{id: w.id, done: true}
+		}
+
+		entries := batch
+		for {
+			select {
+			case batch, ok = <-w.batches:
+				if !ok {
+					return walkBatchMsg{id: w.id, entries: entries, done: true}
+				}
+				entries = append(entries, batch...)
+			default:
+				return walkBatchMsg{id: w.id, entries: entries}
+			}
+		}
+	}
+}
+
+// stop cancels the walk. The walker's goroutine exits at its next send.
+func (w *treeWalker) stop() {
+	if w != nil {
+		w.cancel()
+	}
+}
+
+// indexDirs maps the path of every directory in the tree to its node.
+func indexDirs(node *FileNode, index map[string]*FileNode) {
+	if node == nil || !node.IsDir {
+		return
+	}
+	index[node.Path] = node
+	for _, child := range node.Children {
+		indexDirs(child, index)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStartWalk(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tempDir := writeFiles(t, map[string]string{
+		".gitignore":        "build/\n",
+		"top.md":            "# Top",
+		"docs/guide.md":     "# Guide",
+		"docs/deep/more.md": "# More",
+		"build/out.md":      "# Generated",
+		".hidden/secret.md": "# Hidden",
+		"docs/image.png":    "",
+	})
+
+	walk := startWalk(1, treeFS{}, []string{tempDir}, FinderOptions{})
+	var paths []string
+	for {
+		msg := walk.next()().(walkBatchMsg)
+		if msg.id != 1 {
+			t.Fatalf("Expected walk id 1, got %d", msg.id)
+		}
+		for _, entry := range msg.entries {
+			rel, _ := filepath.Rel(tempDir, entry.path)
+			paths = append(paths, rel)
+		}
+		if msg.done {
+			break
+		}
+	}
+
+	// Breadth first, without hidden, ignored or non-markdown entries
+	expected := []string{"docs", "top.md", "docs/deep", "docs/guide.md", "docs/deep/more.md"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != filepath.FromSlash(expected[i]) {
+			t.Errorf("Entry %d: expected %s, got %s", i, expected[i], paths[i])
+		}
+	}
+
+	// A stopped walk ends without sending everything
+	walk = startWalk(2, treeFS{}, []string{tempDir}, FinderOptions{IncludeIgnored: true})
+	walk.stop()
+	for msg := walk.next()().(walkBatchMsg); !msg.done; msg = walk.next()().(walkBatchMsg) {
+	}
+}
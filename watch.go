@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// watchInterval is how often the displayed file is checked for changes.
+	watchInterval = 500 * time.Millisecond
+	// reloadIndicatorDuration is how long the reload notice stays visible.
+	reloadIndicatorDuration = 2 * time.Second
+)
+
+// fileStamp identifies a version of a file on disk without reading it.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFile returns the stamp of path, or the zero stamp if it can't be read.
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// fileWatchMsg reports the result of one check of the displayed file. The
+// content is only read when the stamp changed.
+type fileWatchMsg struct {
+	path    string
+	stamp   fileStamp
+	content string
+	changed bool
+}
+
+// clearStatusMsg hides a transient status message, unless a newer message
+// has replaced it in the meantime.
+type clearStatusMsg struct {
+	id int
+}
+
+// watchFile checks path for changes after watchInterval. The check runs in the
+// background; each model keeps a single watch going by scheduling the next one
+// when it receives the result, so it always watches the file currently shown.
+func watchFile(path string, stamp fileStamp) tea.Cmd {
+	return tea.Tick(watchInterval, func(t time.Time) tea.Msg {
+		if path == "" {
+			return fileWatchMsg{}
+		}
+
+		current := statFile(path)
+		if current == stamp || current == (fileStamp{}) {
+			// Unchanged, or briefly missing while an editor replaces it
+			return fileWatchMsg{path: path, stamp: stamp}
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fileWatchMsg{path: path, stamp: stamp}
+		}
+		return fileWatchMsg{path: path, stamp: current, content: string(content), changed: true}
+	})
+}
+
+// clearStatusAfter hides the status message with the given id once the reload
+// indicator has been shown long enough.
+func clearStatusAfter(id int) tea.Cmd {
+	return tea.Tick(reloadIndicatorDuration, func(t time.Time) tea.Msg {
+		return clearStatusMsg{id: id}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "md_test_watch")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "live.md")
+	if err := os.WriteFile(path, []byte("# Before\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	stamp := statFile(path)
+
+	msg := watchFile(path, stamp)().(fileWatchMsg)
+	if msg.changed {
+		t.Error("Expected an unchanged file not to be reported")
+	}
+
+	if err := os.WriteFile(path, []byte("# After the edit\n"), 0644); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+	msg = watchFile(path, stamp)().(fileWatchMsg)
+	if !msg.changed || msg.content != "# After the edit\n" {
+		t.Errorf("Expected the new content to be reported, got %+v", msg)
+	}
+
+	// A file that disappears keeps its stamp so reappearing counts as a change
+	os.Remove(path)
+	msg = watchFile(path, stamp)().(fileWatchMsg)
+	if msg.changed || msg.stamp != stamp {
+		t.Errorf("Expected a missing file to keep its stamp, got %+v", msg)
+	}
+}
+
+func TestSingleFileReload(t *testing.T) {
+	model, _ := NewSingleFileModel("live.md")
+	model.raw = true
+	model.height = 10
+	model.Update(fileLoadedMsg{path: "live.md", content: strings.Repeat("line\n", 50)})
+	model.scrollTo(20)
+
+	_, cmd := model.Update(fileWatchMsg{
+		path:    "live.md",
+		stamp:   fileStamp{modTime: time.Now(), size: 1},
+		content: "# Edited\n" + strings.Repeat("line\n", 50),
+		changed: true,
+	})
+	if cmd == nil {
+		t.Fatal("Expected the watch to continue after a reload")
+	}
+	if model.lines[0] != "# Edited" {
+		t.Errorf("Expected reloaded content, got %q", model.lines[0])
+	}
+	if model.viewport != 20 {
+		t.Errorf("Expected viewport to stay at 20, got %d", model.viewport)
+	}
+	if model.statusLine() != "Reloaded" {
+		t.Errorf("Expected reload indicator, got %q", model.statusLine())
+	}
+
+	model.Update(clearStatusMsg{id: model.messageID})
+	if model.statusLine() != "" {
+		t.Errorf("Expected reload indicator to expire, got %q", model.statusLine())
+	}
+}
+
+func TestDualPaneReloadKeepsBottom(t *testing.T) {
+	m := &DualPaneModel{
+		allFiles:       []string{"live.md"},
+		loadedPath:     "live.md",
+		currentContent: strings.Repeat("line\n", 30),
+		height:         12,
+		raw:            true,
+	}
+	m.refreshContent()
+	m.scrollContentTo(len(m.renderedLines))
+
+	m.Update(fileWatchMsg{path: "live.md", content: strings.Repeat("line\n", 40), changed: true})
+
+	if expected := len(m.renderedLines) - (m.height - 2); m.contentViewport != expected {
+		t.Errorf("Expected view to follow the end at %d, got %d", expected, m.contentViewport)
+	}
+	if m.statusMessage != "Reloaded live.md" {
+		t.Errorf("Expected reload indicator, got %q", m.statusMessage)
+	}
+}